@@ -0,0 +1,281 @@
+package gcache
+
+import (
+	"hash/fnv"
+)
+
+const (
+	// adaptiveShadowMin is the smallest number of keys a shadow cache holds.
+	// Keys are sampled so that the shadow caches hold at least this many.
+	adaptiveShadowMin = 64
+	// adaptiveMaxSampleRate bounds the sampling: at least one key in this
+	// many is simulated.
+	adaptiveMaxSampleRate = 16
+	// adaptiveWindow is the number of sampled requests, in multiples of the
+	// shadow size, after which the policies are compared.
+	adaptiveWindow = 4
+	// adaptiveMargin is how much more hits the other policy needs, in percent,
+	// before the cache switches to it.
+	adaptiveMargin = 5
+)
+
+// AdaptiveCache evicts like LRU or LFU, whichever suits the workload better.
+// A sample of the keys is replayed against small shadow LRU and LFU caches,
+// and after every window of sampled requests the cache switches to the
+// policy whose shadow got clearly more hits. Both policies track every key,
+// so a switch takes effect at once. The policy in use is reported by
+// ActivePolicy, and the number of switches by PolicySwitchCount.
+type AdaptiveCache struct {
+	baseCache
+}
+
+var _ Cache = (*AdaptiveCache)(nil)
+
+func newAdaptiveCache(cb *CacheBuilder) *AdaptiveCache {
+	c := &AdaptiveCache{}
+	p := newAdaptivePolicy(cb)
+	buildCache(&c.baseCache, cb, p)
+	p.stats = c.stats
+	c.stats.setActivePolicy(p.activeType())
+	return c
+}
+
+type adaptivePolicy struct {
+	stats    *stats
+	policies [2]*adaptiveCandidate // LRU and LFU
+	active   int
+
+	sampleRate uint64
+	requests   int // sampled requests in the current window
+	// keys without a cheap hash are sampled by counting their insertions,
+	// and the sampled ones kept here
+	inserts uint64
+	sampled map[interface{}]struct{}
+}
+
+// adaptiveCandidate is a policy tracking every key of the cache, together
+// with its shadow cache.
+type adaptiveCandidate struct {
+	tp     string
+	policy Policy
+	shadow *adaptiveShadow
+}
+
+// adaptiveShadow simulates a policy on the sampled keys.
+type adaptiveShadow struct {
+	policy Policy
+	keys   map[interface{}]struct{}
+	size   int
+	hits   int
+}
+
+func newAdaptivePolicy(cb *CacheBuilder) *adaptivePolicy {
+	return &adaptivePolicy{
+		sampled: make(map[interface{}]struct{}),
+		policies: [2]*adaptiveCandidate{
+			{
+				tp:     TYPE_LRU,
+				policy: newLRUPolicy(),
+				shadow: newAdaptiveShadow(newLRUPolicy()),
+			},
+			{
+				tp:     TYPE_LFU,
+				policy: newLFUPolicy(cb),
+				shadow: newAdaptiveShadow(newLFUPolicy(cb)),
+			},
+		},
+	}
+}
+
+func newAdaptiveShadow(p Policy) *adaptiveShadow {
+	return &adaptiveShadow{
+		policy: p,
+		keys:   make(map[interface{}]struct{}),
+	}
+}
+
+func (p *adaptivePolicy) activeType() string {
+	return p.policies[p.active].tp
+}
+
+func (p *adaptivePolicy) reset() {
+	for _, c := range p.policies {
+		c.policy.(resettablePolicy).reset()
+	}
+	p.sampled = make(map[interface{}]struct{})
+}
+
+// SetSize samples enough keys for the shadow caches to hold
+// adaptiveShadowMin of them.
+func (p *adaptivePolicy) SetSize(size int) {
+	p.sampleRate = uint64(minInt(adaptiveMaxSampleRate, maxInt(1, size/adaptiveShadowMin)))
+	for _, c := range p.policies {
+		c.shadow.setSize(maxInt(1, size/int(p.sampleRate)))
+	}
+}
+
+func (p *adaptivePolicy) OnInsert(key interface{}) {
+	for _, c := range p.policies {
+		c.policy.OnInsert(key)
+	}
+	if _, ok := hashKey(key); !ok && p.sampleRate > 1 {
+		p.inserts++
+		if p.inserts%p.sampleRate == 0 {
+			p.sampled[key] = struct{}{}
+		}
+	}
+	p.sample(key)
+}
+
+func (p *adaptivePolicy) OnAccess(key interface{}) {
+	for _, c := range p.policies {
+		c.policy.OnAccess(key)
+	}
+	p.sample(key)
+}
+
+func (p *adaptivePolicy) OnUpdate(key interface{}) {
+	for _, c := range p.policies {
+		if u, ok := c.policy.(UpdatePolicy); ok {
+			u.OnUpdate(key)
+		} else {
+			c.policy.OnAccess(key)
+		}
+	}
+	p.sample(key)
+}
+
+func (p *adaptivePolicy) OnRemove(key interface{}) {
+	for _, c := range p.policies {
+		c.policy.OnRemove(key)
+	}
+	delete(p.sampled, key)
+}
+
+// Victim asks the active policy for a key, and makes the other one forget it.
+func (p *adaptivePolicy) Victim() (interface{}, bool) {
+	key, ok := p.policies[p.active].policy.Victim()
+	if ok {
+		p.policies[1-p.active].policy.OnRemove(key)
+		delete(p.sampled, key)
+	}
+	return key, ok
+}
+
+func (p *adaptivePolicy) inspect(key interface{}, e *Entry) {
+	if ip, ok := p.policies[p.active].policy.(inspectablePolicy); ok {
+		ip.inspect(key, e)
+	}
+}
+
+func (p *adaptivePolicy) order() []interface{} {
+	return p.policies[p.active].policy.(orderedPolicy).order()
+}
+
+// sample replays a request for a sampled key against the shadow caches, and
+// switches policies at the end of a window.
+func (p *adaptivePolicy) sample(key interface{}) {
+	if !p.isSampled(key) {
+		return
+	}
+	for _, c := range p.policies {
+		c.shadow.request(key)
+	}
+	p.requests++
+	if p.requests < adaptiveWindow*p.policies[0].shadow.size {
+		return
+	}
+	active, other := p.policies[p.active].shadow, p.policies[1-p.active].shadow
+	if other.hits*100 > active.hits*(100+adaptiveMargin) {
+		p.active = 1 - p.active
+		if p.stats != nil {
+			p.stats.IncrPolicySwitchCount()
+			p.stats.setActivePolicy(p.activeType())
+		}
+	}
+	p.requests = 0
+	for _, c := range p.policies {
+		c.shadow.hits = 0
+	}
+}
+
+// isSampled reports whether requests for key are replayed against the shadow
+// caches.
+func (p *adaptivePolicy) isSampled(key interface{}) bool {
+	if p.sampleRate <= 1 {
+		return true
+	}
+	if h, ok := hashKey(key); ok {
+		return h%p.sampleRate == 0
+	}
+	_, ok := p.sampled[key]
+	return ok
+}
+
+func (s *adaptiveShadow) setSize(size int) {
+	s.size = size
+	for len(s.keys) > size {
+		s.evict()
+	}
+}
+
+// request records a hit if key is in the shadow cache, and inserts it
+// otherwise.
+func (s *adaptiveShadow) request(key interface{}) {
+	if _, ok := s.keys[key]; ok {
+		s.hits++
+		s.policy.OnAccess(key)
+		return
+	}
+	if len(s.keys) >= s.size {
+		s.evict()
+	}
+	s.keys[key] = struct{}{}
+	s.policy.OnInsert(key)
+}
+
+func (s *adaptiveShadow) evict() {
+	if key, ok := s.policy.Victim(); ok {
+		delete(s.keys, key)
+	}
+}
+
+// hashKey hashes a key for sampling, if it is a string or an integer.
+func hashKey(key interface{}) (uint64, bool) {
+	var n uint64
+	switch k := key.(type) {
+	case string:
+		h := fnv.New64a()
+		h.Write([]byte(k))
+		return h.Sum64(), true
+	case int:
+		n = uint64(k)
+	case int8:
+		n = uint64(k)
+	case int16:
+		n = uint64(k)
+	case int32:
+		n = uint64(k)
+	case int64:
+		n = uint64(k)
+	case uint:
+		n = uint64(k)
+	case uint8:
+		n = uint64(k)
+	case uint16:
+		n = uint64(k)
+	case uint32:
+		n = uint64(k)
+	case uint64:
+		n = k
+	default:
+		return 0, false
+	}
+	// mix the bits so that sampling does not follow arithmetic patterns
+	n ^= n >> 33
+	n *= 0xff51afd7ed558ccd
+	n ^= n >> 33
+	n *= 0xc4ceb9fe1a85ec53
+	n ^= n >> 33
+	return n, true
+}
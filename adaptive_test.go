@@ -0,0 +1,246 @@
+package gcache
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+	"time"
+)
+
+func TestAdaptiveGet(t *testing.T) {
+	size := 1000
+	gc := buildTestCache(t, TYPE_ADAPTIVE, size)
+	testSetCache(t, gc, size)
+	testGetCache(t, gc, size)
+}
+
+func TestLoadingAdaptiveGet(t *testing.T) {
+	size := 1000
+	numbers := 1000
+	testGetCache(t, buildTestLoadingCache(t, TYPE_ADAPTIVE, size, loader), numbers)
+}
+
+func TestAdaptiveLength(t *testing.T) {
+	gc := buildTestLoadingCacheWithExpiration(t, TYPE_ADAPTIVE, 2, time.Millisecond)
+	gc.Get("test1")
+	gc.Get("test2")
+	gc.Get("test3")
+	length := gc.Len(true)
+	expectedLength := 2
+	if length != expectedLength {
+		t.Errorf("Expected length is %v, not %v", expectedLength, length)
+	}
+	time.Sleep(time.Millisecond)
+	gc.Get("test4")
+	length = gc.Len(true)
+	expectedLength = 1
+	if length != expectedLength {
+		t.Errorf("Expected length is %v, not %v", expectedLength, length)
+	}
+}
+
+func TestAdaptiveEvictItem(t *testing.T) {
+	cacheSize := 10
+	numbers := cacheSize + 1
+	gc := buildTestLoadingCache(t, TYPE_ADAPTIVE, cacheSize, loader)
+
+	for i := 0; i < numbers; i++ {
+		_, err := gc.Get(fmt.Sprintf("Key-%d", i))
+		if err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+	}
+	if l := gc.Len(false); l != cacheSize {
+		t.Errorf("Expected length is %v, not %v", cacheSize, l)
+	}
+}
+
+func TestAdaptivePurgeCache(t *testing.T) {
+	cacheSize := 10
+	purgeCount := 0
+	gc := New(cacheSize).
+		Adaptive().
+		LoaderFunc(loader).
+		PurgeVisitorFunc(func(k, v interface{}) {
+			purgeCount++
+		}).
+		Build()
+
+	for i := 0; i < cacheSize; i++ {
+		_, err := gc.Get(fmt.Sprintf("Key-%d", i))
+		if err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+	}
+
+	gc.Purge()
+
+	if purgeCount != cacheSize {
+		t.Errorf("failed to purge everything")
+	}
+}
+
+func TestAdaptiveGetIFPresent(t *testing.T) {
+	testGetIFPresent(t, TYPE_ADAPTIVE)
+}
+
+func TestAdaptiveHas(t *testing.T) {
+	gc := buildTestLoadingCacheWithExpiration(t, TYPE_ADAPTIVE, 2, 10*time.Millisecond)
+
+	for i := 0; i < 10; i++ {
+		t.Run(fmt.Sprint(i), func(t *testing.T) {
+			gc.Get("test1")
+			gc.Get("test2")
+
+			if gc.Has("test0") {
+				t.Fatal("should not have test0")
+			}
+			if !gc.Has("test1") {
+				t.Fatal("should have test1")
+			}
+			if !gc.Has("test2") {
+				t.Fatal("should have test2")
+			}
+
+			time.Sleep(20 * time.Millisecond)
+
+			if gc.Has("test0") {
+				t.Fatal("should not have test0")
+			}
+			if gc.Has("test1") {
+				t.Fatal("should not have test1")
+			}
+			if gc.Has("test2") {
+				t.Fatal("should not have test2")
+			}
+		})
+	}
+}
+
+// adaptiveRequest gets key, setting it on a miss.
+func adaptiveRequest(gc Cache, key interface{}) {
+	if _, err := gc.Get(key); err != nil {
+		gc.Set(key, key)
+	}
+}
+
+func TestAdaptiveSwitches(t *testing.T) {
+	size := 100
+	gc := buildTestCache(t, TYPE_ADAPTIVE, size)
+	if tp := gc.ActivePolicy(); tp != TYPE_LRU {
+		t.Fatalf("should start with LRU, got %v", tp)
+	}
+
+	// popular keys mixed with a scan of keys seen once: LFU keeps the
+	// popular keys while LRU lets the scan flush them
+	rnd := rand.New(rand.NewSource(1))
+	for i := 0; i < 20000; i++ {
+		if rnd.Intn(5) == 0 {
+			adaptiveRequest(gc, rnd.Intn(50))
+		} else {
+			adaptiveRequest(gc, fmt.Sprintf("scan-%d", i))
+		}
+	}
+	if tp := gc.ActivePolicy(); tp != TYPE_LFU {
+		t.Fatalf("should switch to LFU, got %v", tp)
+	}
+	switches := gc.PolicySwitchCount()
+	if switches == 0 {
+		t.Fatal("should count the switch")
+	}
+
+	// a new working set that fits into the cache: LRU keeps all of it while
+	// LFU holds on to the old popular keys
+	for i := 0; i < 20000; i++ {
+		adaptiveRequest(gc, 1000+i%90)
+	}
+	if tp := gc.ActivePolicy(); tp != TYPE_LRU {
+		t.Fatalf("should switch back to LRU, got %v", tp)
+	}
+	if n := gc.PolicySwitchCount(); n <= switches {
+		t.Fatalf("should count the second switch: %v <= %v", n, switches)
+	}
+	for i := 0; i < 90; i++ {
+		if !gc.Has(1000 + i) {
+			t.Fatalf("%v should be cached", 1000+i)
+		}
+	}
+}
+
+func TestAdaptiveSampling(t *testing.T) {
+	size := 64 * 100
+	gc := New(size).Adaptive().Build().(*AdaptiveCache)
+	p := gc.policy.(*adaptivePolicy)
+	if p.sampleRate != adaptiveMaxSampleRate {
+		t.Fatalf("%v != %v", p.sampleRate, adaptiveMaxSampleRate)
+	}
+	setItemsByRange(t, gc, 0, 2*size)
+	for _, c := range p.policies {
+		if n := len(c.shadow.keys); n > c.shadow.size || n == 0 {
+			t.Fatalf("%v shadow holds %v keys, size %v", c.tp, n, c.shadow.size)
+		}
+	}
+	if l := gc.Len(false); l != size {
+		t.Fatalf("%v != %v", l, size)
+	}
+}
+
+func TestActivePolicy(t *testing.T) {
+	for _, tp := range []string{TYPE_SIMPLE, TYPE_LRU, TYPE_ARC, TYPE_GDSF} {
+		if a := New(10).EvictType(tp).Build().ActivePolicy(); a != tp {
+			t.Errorf("%v != %v", a, tp)
+		}
+	}
+	if a := New(10).Policy(func() Policy { return newFIFOPolicy() }).Build().ActivePolicy(); a != "" {
+		t.Errorf("a custom policy should have no type, got %v", a)
+	}
+}
+
+func TestAdaptiveConcurrentGet(t *testing.T) {
+	testConcurrentGet(t, TYPE_ADAPTIVE)
+}
+
+func TestAdaptiveOrderAndEntry(t *testing.T) {
+	gc := New(3).Adaptive().Build().(*AdaptiveCache)
+	p := gc.policy.(*adaptivePolicy)
+	p.active = 1 // LFU
+	setItemsByRange(t, gc, 0, 3)
+	for i := 0; i < 2; i++ {
+		gc.Get(0)
+	}
+	gc.Get(1)
+
+	var keys []interface{}
+	gc.Ascend(func(key, value interface{}) bool {
+		keys = append(keys, key)
+		return true
+	})
+	if len(keys) != 3 || keys[0] != 2 || keys[1] != 1 || keys[2] != 0 {
+		t.Fatalf("%v is not ordered by frequency", keys)
+	}
+	if e, _ := gc.GetEntry(0); e.Frequency != 2 {
+		t.Fatalf("%v != 2", e.Frequency)
+	}
+}
+
+func TestAdaptiveSamplingWithoutHash(t *testing.T) {
+	type key struct{ n int }
+	size := 64 * 100
+	gc := New(size).Adaptive().Build().(*AdaptiveCache)
+	p := gc.policy.(*adaptivePolicy)
+	for i := 0; i < 2*size; i++ {
+		gc.Set(key{i}, i)
+	}
+	if n := len(p.sampled); n == 0 || n > size/adaptiveMaxSampleRate {
+		t.Fatalf("%v sampled keys, expected at most %v", n, size/adaptiveMaxSampleRate)
+	}
+	for _, c := range p.policies {
+		if n := len(c.shadow.keys); n > c.shadow.size || n == 0 {
+			t.Fatalf("%v shadow holds %v keys, size %v", c.tp, n, c.shadow.size)
+		}
+	}
+	gc.Purge()
+	if n := len(p.sampled); n != 0 {
+		t.Fatalf("%v sampled keys after a purge", n)
+	}
+}
@@ -2,396 +2,148 @@ package gcache
 
 import (
 	"container/list"
-	"time"
 )
 
 // Constantly balances between LRU and LFU, to improve the combined result.
 type ARC struct {
 	baseCache
-	items map[interface{}]*arcItem
-
-	part int
-	t1   *arcList
-	t2   *arcList
-	b1   *arcList
-	b2   *arcList
 }
 
+var _ Cache = (*ARC)(nil)
+
 func newARC(cb *CacheBuilder) *ARC {
 	c := &ARC{}
-	buildCache(&c.baseCache, cb)
-
-	c.init()
-	c.loadGroup.cache = c
+	buildCache(&c.baseCache, cb, newARCPolicy())
 	return c
 }
 
-func (c *ARC) init() {
-	c.items = make(map[interface{}]*arcItem)
-	c.t1 = newARCList()
-	c.t2 = newARCList()
-	c.b1 = newARCList()
-	c.b2 = newARCList()
-}
-
-func (c *ARC) replace(key interface{}) {
-	if !c.isCacheFull() {
-		return
-	}
-	var old interface{}
-	if c.t1.Len() > 0 && ((c.b2.Has(key) && c.t1.Len() == c.part) || (c.t1.Len() > c.part)) {
-		old = c.t1.RemoveTail()
-		c.b1.PushFront(old)
-	} else if c.t2.Len() > 0 {
-		old = c.t2.RemoveTail()
-		c.b2.PushFront(old)
-	} else {
-		old = c.t1.RemoveTail()
-		c.b1.PushFront(old)
-	}
-	item, ok := c.items[old]
-	if ok {
-		delete(c.items, old)
-		if c.evictedFunc != nil {
-			c.evictedFunc(item.key, item.value)
-		}
-	}
-}
-
-func (c *ARC) Set(key, value interface{}) error {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-	_, err := c.set(key, value)
-	return err
-}
-
-// Set a new key-value pair with an expiration time
-func (c *ARC) SetWithExpire(key, value interface{}, expiration time.Duration) error {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-	item, err := c.set(key, value)
-	if err != nil {
-		return err
-	}
-
-	t := c.clock.Now().Add(expiration)
-	item.(*arcItem).expiration = &t
-	return nil
-}
-
-func (c *ARC) set(key, value interface{}) (interface{}, error) {
-	var err error
-	if c.serializeFunc != nil {
-		value, err = c.serializeFunc(key, value)
-		if err != nil {
-			return nil, err
-		}
-	}
-
-	item, ok := c.items[key]
-	if ok {
-		item.value = value
-	} else {
-		item = &arcItem{
-			clock: c.clock,
-			key:   key,
-			value: value,
-		}
-		c.items[key] = item
-	}
-
-	if c.expiration != nil {
-		t := c.clock.Now().Add(*c.expiration)
-		item.expiration = &t
-	}
-
-	defer func() {
-		if c.addedFunc != nil {
-			c.addedFunc(key, value)
-		}
-	}()
-
-	if c.t1.Has(key) || c.t2.Has(key) {
-		return item, nil
-	}
-
-	if elt := c.b1.Lookup(key); elt != nil {
-		c.setPart(minInt(c.size, c.part+maxInt(c.b2.Len()/c.b1.Len(), 1)))
-		c.replace(key)
-		c.b1.Remove(key, elt)
-		c.t2.PushFront(key)
-		return item, nil
-	}
-
-	if elt := c.b2.Lookup(key); elt != nil {
-		c.setPart(maxInt(0, c.part-maxInt(c.b1.Len()/c.b2.Len(), 1)))
-		c.replace(key)
-		c.b2.Remove(key, elt)
-		c.t2.PushFront(key)
-		return item, nil
-	}
-
-	if c.isCacheFull() && c.t1.Len()+c.b1.Len() == c.size {
-		if c.t1.Len() < c.size {
-			c.b1.RemoveTail()
-			c.replace(key)
-		} else {
-			pop := c.t1.RemoveTail()
-			item, ok := c.items[pop]
-			if ok {
-				delete(c.items, pop)
-				if c.evictedFunc != nil {
-					c.evictedFunc(item.key, item.value)
-				}
-			}
-		}
-	} else {
-		total := c.t1.Len() + c.b1.Len() + c.t2.Len() + c.b2.Len()
-		if total >= c.size {
-			if total == (2 * c.size) {
-				if c.b2.Len() > 0 {
-					c.b2.RemoveTail()
-				} else {
-					c.b1.RemoveTail()
-				}
-			}
-			c.replace(key)
-		}
-	}
-	c.t1.PushFront(key)
-	return item, nil
-}
-
-// Get a value from cache pool using key if it exists. If not exists and it has LoaderFunc, it will generate the value using you have specified LoaderFunc method returns value.
-func (c *ARC) Get(key interface{}) (interface{}, error) {
-	v, err := c.get(key, false)
-	if err == KeyNotFoundError {
-		return c.getWithLoader(key, true)
-	}
-	return v, err
+// arcPolicy keeps the keys seen once in t1 and the keys seen again in t2.
+// The keys evicted from them are remembered in the ghost lists b1 and b2,
+// and a hit in a ghost list moves the target size of t1 towards the list
+// that would have kept the key.
+type arcPolicy struct {
+	size int
+	part int // target size of t1
+	t1   *arcList
+	t2   *arcList
+	b1   *arcList
+	b2   *arcList
 }
 
-// GetIFPresent gets a value from cache pool using key if it exists.
-// If it does not exists key, returns KeyNotFoundError.
-// And send a request which refresh value for specified key if cache object has LoaderFunc.
-func (c *ARC) GetIFPresent(key interface{}) (interface{}, error) {
-	v, err := c.get(key, false)
-	if err == KeyNotFoundError {
-		return c.getWithLoader(key, false)
-	}
-	return v, err
+func newARCPolicy() *arcPolicy {
+	p := &arcPolicy{}
+	p.reset()
+	return p
 }
 
-func (c *ARC) get(key interface{}, onLoad bool) (interface{}, error) {
-	v, err := c.getValue(key, onLoad)
-	if err != nil {
-		return nil, err
-	}
-	if c.deserializeFunc != nil {
-		return c.deserializeFunc(key, v)
-	}
-	return v, nil
+func (p *arcPolicy) reset() {
+	p.part = 0
+	p.t1 = newARCList()
+	p.t2 = newARCList()
+	p.b1 = newARCList()
+	p.b2 = newARCList()
 }
 
-func (c *ARC) getValue(key interface{}, onLoad bool) (interface{}, error) {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-	if elt := c.t1.Lookup(key); elt != nil {
-		c.t1.Remove(key, elt)
-		item := c.items[key]
-		if !item.IsExpired(nil) {
-			c.t2.PushFront(key)
-			if !onLoad {
-				c.stats.IncrHitCount()
-			}
-			return item.value, nil
-		} else {
-			delete(c.items, key)
-			c.b1.PushFront(key)
-			if c.evictedFunc != nil {
-				c.evictedFunc(item.key, item.value)
-			}
-		}
-	}
-	if elt := c.t2.Lookup(key); elt != nil {
-		item := c.items[key]
-		if !item.IsExpired(nil) {
-			c.t2.MoveToFront(elt)
-			if !onLoad {
-				c.stats.IncrHitCount()
-			}
-			return item.value, nil
-		} else {
-			delete(c.items, key)
-			c.t2.Remove(key, elt)
-			c.b2.PushFront(key)
-			if c.evictedFunc != nil {
-				c.evictedFunc(item.key, item.value)
-			}
-		}
-	}
-
-	if !onLoad {
-		c.stats.IncrMissCount()
-	}
-	return nil, KeyNotFoundError
+// SetSize changes the capacity and trims the ghost lists to it.
+func (p *arcPolicy) SetSize(size int) {
+	p.size = size
+	p.part = minInt(p.part, size)
+	p.trimGhosts()
 }
 
-func (c *ARC) getWithLoader(key interface{}, isWait bool) (interface{}, error) {
-	if c.loaderExpireFunc == nil {
-		return nil, KeyNotFoundError
+func (p *arcPolicy) OnInsert(key interface{}) {
+	if elt := p.b1.Lookup(key); elt != nil {
+		p.part = minInt(p.size, p.part+maxInt(p.b2.Len()/p.b1.Len(), 1))
+		p.b1.Remove(key, elt)
+		p.t2.PushFront(key)
+		return
 	}
-	value, _, err := c.load(key, func(v interface{}, expiration *time.Duration, e error) (interface{}, error) {
-		if e != nil {
-			return nil, e
-		}
-		c.mu.Lock()
-		defer c.mu.Unlock()
-		item, err := c.set(key, v)
-		if err != nil {
-			return nil, err
-		}
-		if expiration != nil {
-			t := c.clock.Now().Add(*expiration)
-			item.(*arcItem).expiration = &t
-		}
-		return v, nil
-	}, isWait)
-	if err != nil {
-		return nil, err
+	if elt := p.b2.Lookup(key); elt != nil {
+		p.part = maxInt(0, p.part-maxInt(p.b1.Len()/p.b2.Len(), 1))
+		p.b2.Remove(key, elt)
+		p.t2.PushFront(key)
+		return
 	}
-	return value, nil
+	p.t1.PushFront(key)
+	p.trimGhosts()
 }
 
-// Has checks if key exists in cache
-func (c *ARC) Has(key interface{}) bool {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
-	now := time.Now()
-	return c.has(key, &now)
-}
-
-func (c *ARC) has(key interface{}, now *time.Time) bool {
-	item, ok := c.items[key]
-	if !ok {
-		return false
+func (p *arcPolicy) OnAccess(key interface{}) {
+	if elt := p.t1.Lookup(key); elt != nil {
+		p.t1.Remove(key, elt)
+		p.t2.PushFront(key)
+	} else if elt := p.t2.Lookup(key); elt != nil {
+		p.t2.MoveToFront(elt)
 	}
-	return !item.IsExpired(now)
 }
 
-// Remove removes the provided key from the cache.
-func (c *ARC) Remove(key interface{}) bool {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-
-	return c.remove(key)
-}
+// OnUpdate leaves key in its list: only reads move it to t2.
+func (p *arcPolicy) OnUpdate(key interface{}) {}
 
-func (c *ARC) remove(key interface{}) bool {
-	if elt := c.t1.Lookup(key); elt != nil {
-		c.t1.Remove(key, elt)
-		item := c.items[key]
-		delete(c.items, key)
-		c.b1.PushFront(key)
-		if c.evictedFunc != nil {
-			c.evictedFunc(key, item.value)
-		}
-		return true
+func (p *arcPolicy) OnRemove(key interface{}) {
+	if elt := p.t1.Lookup(key); elt != nil {
+		p.t1.Remove(key, elt)
+		p.b1.PushFront(key)
+	} else if elt := p.t2.Lookup(key); elt != nil {
+		p.t2.Remove(key, elt)
+		p.b2.PushFront(key)
 	}
-
-	if elt := c.t2.Lookup(key); elt != nil {
-		c.t2.Remove(key, elt)
-		item := c.items[key]
-		delete(c.items, key)
-		c.b2.PushFront(key)
-		if c.evictedFunc != nil {
-			c.evictedFunc(key, item.value)
-		}
-		return true
-	}
-
-	return false
+	p.trimGhosts()
 }
 
-// GetALL returns all key-value pairs in the cache.
-func (c *ARC) GetALL(checkExpired bool) map[interface{}]interface{} {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
-	items := make(map[interface{}]interface{}, len(c.items))
-	now := time.Now()
-	for k, item := range c.items {
-		if !checkExpired || c.has(k, &now) {
-			items[k] = item.value
-		}
+func (p *arcPolicy) inspect(key interface{}, e *Entry) {
+	if p.t1.Has(key) {
+		e.List = "t1"
+	} else if p.t2.Has(key) {
+		e.List = "t2"
 	}
-	return items
 }
 
-// Keys returns a slice of the keys in the cache.
-func (c *ARC) Keys(checkExpired bool) []interface{} {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
-	keys := make([]interface{}, 0, len(c.items))
-	now := time.Now()
-	for k := range c.items {
-		if !checkExpired || c.has(k, &now) {
-			keys = append(keys, k)
+// order lists the keys seen once before the keys seen again, which is the
+// eviction order while t1 is over its target size.
+func (p *arcPolicy) order() []interface{} {
+	keys := make([]interface{}, 0, p.t1.Len()+p.t2.Len())
+	for _, al := range []*arcList{p.t1, p.t2} {
+		for elt := al.l.Back(); elt != nil; elt = elt.Prev() {
+			keys = append(keys, elt.Value)
 		}
 	}
 	return keys
 }
 
-// Len returns the number of items in the cache.
-func (c *ARC) Len(checkExpired bool) int {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
-	if !checkExpired {
-		return len(c.items)
-	}
-	var length int
-	now := time.Now()
-	for k := range c.items {
-		if c.has(k, &now) {
-			length++
-		}
-	}
-	return length
-}
-
-// Purge is used to completely clear the cache
-func (c *ARC) Purge() {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-
-	if c.purgeVisitorFunc != nil {
-		for _, item := range c.items {
-			c.purgeVisitorFunc(item.key, item.value)
+// Victim evicts from t1 while it is over its target size, and from t2
+// otherwise. The key is remembered in the matching ghost list.
+func (p *arcPolicy) Victim() (interface{}, bool) {
+	var key interface{}
+	switch {
+	case p.t1.Len() > 0 && (p.t1.Len() > p.part || p.t2.Len() == 0):
+		key = p.t1.RemoveTail()
+		p.b1.PushFront(key)
+	case p.t2.Len() > 0:
+		key = p.t2.RemoveTail()
+		p.b2.PushFront(key)
+	default:
+		return nil, false
+	}
+	p.trimGhosts()
+	return key, true
+}
+
+// trimGhosts bounds t1 and b1 together to the size, and all lists together
+// to twice the size.
+func (p *arcPolicy) trimGhosts() {
+	for p.b1.Len() > 0 && p.t1.Len()+p.b1.Len() > p.size {
+		p.b1.RemoveTail()
+	}
+	for p.t1.Len()+p.t2.Len()+p.b1.Len()+p.b2.Len() > 2*p.size {
+		if p.b2.Len() > 0 {
+			p.b2.RemoveTail()
+		} else if p.b1.Len() > 0 {
+			p.b1.RemoveTail()
+		} else {
+			return
 		}
 	}
-
-	c.init()
-}
-
-func (c *ARC) setPart(p int) {
-	if c.isCacheFull() {
-		c.part = p
-	}
-}
-
-func (c *ARC) isCacheFull() bool {
-	return (c.t1.Len() + c.t2.Len()) == c.size
-}
-
-// IsExpired returns boolean value whether this item is expired or not.
-func (it *arcItem) IsExpired(now *time.Time) bool {
-	if it.expiration == nil {
-		return false
-	}
-	if now == nil {
-		t := it.clock.Now()
-		now = &t
-	}
-	return it.expiration.Before(*now)
 }
 
 type arcList struct {
@@ -399,13 +151,6 @@ type arcList struct {
 	keys map[interface{}]*list.Element
 }
 
-type arcItem struct {
-	clock      Clock
-	key        interface{}
-	value      interface{}
-	expiration *time.Time
-}
-
 func newARCList() *arcList {
 	return &arcList{
 		l:    list.New(),
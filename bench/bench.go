@@ -0,0 +1,197 @@
+// Package bench generates synthetic key streams and drives caches with
+// them, reporting throughput, tail latency and hit ratio.
+//
+//	results := bench.Run(bench.Config{
+//		Builder:  gcache.New(10000).LRU(),
+//		Workload: bench.Workload{Name: "zipf", Keys: bench.Zipf(100000, 0.99), ReadRatio: 0.9},
+//		Procs:    []int{1, 4, 16},
+//	})
+//	for _, r := range results {
+//		fmt.Println(r)
+//	}
+package bench
+
+import (
+	"fmt"
+	"runtime"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/bluele/gcache"
+)
+
+// DefaultOps is the number of operations of a run if Config.Ops is 0.
+const DefaultOps = 1000000
+
+// Workload describes the requests sent to a cache.
+type Workload struct {
+	Name string
+	Keys GeneratorFunc
+	// ReadRatio is the share of reads in [0, 1]; the rest are writes.
+	// A read that misses writes the key, as a read-through cache would.
+	ReadRatio float64
+}
+
+// Workloads returns a set of common workloads over n keys.
+func Workloads(n uint64) []Workload {
+	return []Workload{
+		{Name: "zipf", Keys: Zipf(n, 0.99), ReadRatio: 0.95},
+		{Name: "scrambled-zipf", Keys: ScrambledZipf(n, 0.99), ReadRatio: 0.95},
+		{Name: "uniform", Keys: Uniform(n), ReadRatio: 0.95},
+		{Name: "hotspot", Keys: Hotspot(n, 0.2, 0.8), ReadRatio: 0.95},
+		{Name: "scan", Keys: Sequential(n), ReadRatio: 1},
+		{Name: "zipf-write-heavy", Keys: Zipf(n, 0.99), ReadRatio: 0.5},
+	}
+}
+
+// Config describes a benchmark.
+type Config struct {
+	// Builder builds a new cache for every run.
+	Builder  *gcache.CacheBuilder
+	Workload Workload
+	// Ops is the total number of operations of a run, shared by its
+	// workers. It defaults to DefaultOps.
+	Ops int
+	// Procs lists the GOMAXPROCS settings to run with, with one worker per
+	// processor. It defaults to the current setting.
+	Procs []int
+	// Seed makes the key streams reproducible.
+	Seed int64
+}
+
+// Result is the outcome of one run.
+type Result struct {
+	Workload   string
+	Procs      int
+	Ops        int
+	Duration   time.Duration
+	Throughput float64 // operations per second
+	HitRatio   float64 // hits per read
+	P50        time.Duration
+	P99        time.Duration
+	P999       time.Duration
+	Max        time.Duration
+}
+
+func (r Result) String() string {
+	return fmt.Sprintf("%s procs=%d ops=%d %.0f ops/s hit=%.2f%% p50=%v p99=%v p99.9=%v max=%v",
+		r.Workload, r.Procs, r.Ops, r.Throughput, 100*r.HitRatio, r.P50, r.P99, r.P999, r.Max)
+}
+
+// Run builds a cache for every GOMAXPROCS setting of cfg and drives it
+// with the workload. GOMAXPROCS is restored afterwards.
+func Run(cfg Config) []Result {
+	if cfg.Ops <= 0 {
+		cfg.Ops = DefaultOps
+	}
+	procs := cfg.Procs
+	if len(procs) == 0 {
+		procs = []int{runtime.GOMAXPROCS(0)}
+	}
+	defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(0))
+
+	results := make([]Result, 0, len(procs))
+	for _, p := range procs {
+		runtime.GOMAXPROCS(p)
+		results = append(results, run(cfg, p))
+	}
+	return results
+}
+
+func run(cfg Config, workers int) Result {
+	gc := cfg.Builder.Build()
+	ops := cfg.Ops / workers
+	latencies := make([][]time.Duration, workers)
+
+	var wg sync.WaitGroup
+	start := time.Now()
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			latencies[w] = drive(gc, cfg.Workload, cfg.Seed+int64(w), ops)
+		}(w)
+	}
+	wg.Wait()
+	elapsed := time.Since(start)
+
+	all := make([]time.Duration, 0, ops*workers)
+	for _, l := range latencies {
+		all = append(all, l...)
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i] < all[j] })
+
+	r := Result{
+		Workload: cfg.Workload.Name,
+		Procs:    workers,
+		Ops:      len(all),
+		Duration: elapsed,
+		HitRatio: gc.HitRate(),
+		P50:      percentile(all, 0.5),
+		P99:      percentile(all, 0.99),
+		P999:     percentile(all, 0.999),
+	}
+	if len(all) > 0 {
+		r.Max = all[len(all)-1]
+	}
+	if elapsed > 0 {
+		r.Throughput = float64(len(all)) / elapsed.Seconds()
+	}
+	return r
+}
+
+// drive sends ops requests to gc and returns the latency of each.
+func drive(gc gcache.Cache, wl Workload, seed int64, ops int) []time.Duration {
+	s := newStream(wl, seed)
+	latencies := make([]time.Duration, ops)
+	for i := range latencies {
+		key, read := s.next()
+		start := time.Now()
+		do(gc, key, read)
+		latencies[i] = time.Since(start)
+	}
+	return latencies
+}
+
+// stream yields the keys of a workload and whether to read or write them.
+type stream struct {
+	keys      Generator
+	mix       Generator
+	threshold uint64
+}
+
+func newStream(wl Workload, seed int64) *stream {
+	return &stream{
+		keys: wl.Keys(seed),
+		// a separate stream decides between reads and writes, so that the
+		// keys do not depend on the read ratio
+		mix:       Uniform(1 << 20)(^seed),
+		threshold: uint64(wl.ReadRatio * (1 << 20)),
+	}
+}
+
+func (s *stream) next() (key uint64, read bool) {
+	return s.keys.Next(), s.mix.Next() < s.threshold
+}
+
+// do sends a request to gc. A read that misses writes the key.
+func do(gc gcache.Cache, key uint64, read bool) {
+	if !read {
+		gc.Set(key, key)
+	} else if _, err := gc.GetIFPresent(key); err != nil {
+		gc.Set(key, key)
+	}
+}
+
+// percentile returns the q-th quantile of the sorted latencies.
+func percentile(sorted []time.Duration, q float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	i := int(q * float64(len(sorted)))
+	if i >= len(sorted) {
+		i = len(sorted) - 1
+	}
+	return sorted[i]
+}
@@ -0,0 +1,146 @@
+package bench
+
+import (
+	"fmt"
+	"sync/atomic"
+	"testing"
+
+	"github.com/bluele/gcache"
+)
+
+func TestGeneratorsRange(t *testing.T) {
+	n := uint64(1000)
+	for _, wl := range Workloads(n) {
+		g := wl.Keys(1)
+		for i := 0; i < 10000; i++ {
+			if k := g.Next(); k >= n {
+				t.Fatalf("%v: key %v out of range", wl.Name, k)
+			}
+		}
+	}
+}
+
+func TestGeneratorsDeterministic(t *testing.T) {
+	for _, wl := range Workloads(1000) {
+		a, b := wl.Keys(7), wl.Keys(7)
+		for i := 0; i < 1000; i++ {
+			if x, y := a.Next(), b.Next(); x != y {
+				t.Fatalf("%v: %v != %v", wl.Name, x, y)
+			}
+		}
+	}
+}
+
+func TestZipfSkew(t *testing.T) {
+	n := uint64(10000)
+	samples := 100000
+	counts := make(map[uint64]int)
+	g := Zipf(n, 0.99)(1)
+	for i := 0; i < samples; i++ {
+		counts[g.Next()]++
+	}
+	// the first 1% of the keys gets far more than 1% of the requests
+	var top int
+	for k := uint64(0); k < n/100; k++ {
+		top += counts[k]
+	}
+	if top < samples/3 {
+		t.Fatalf("top keys got %v of %v requests", top, samples)
+	}
+	if counts[0] <= counts[1] || counts[1] <= counts[10] {
+		t.Fatalf("popularity should decrease: %v, %v, %v", counts[0], counts[1], counts[10])
+	}
+
+	// scrambling keeps the skew but moves the popular keys
+	counts = make(map[uint64]int)
+	g = ScrambledZipf(n, 0.99)(1)
+	max := 0
+	for i := 0; i < samples; i++ {
+		k := g.Next()
+		counts[k]++
+		if counts[k] > max {
+			max = counts[k]
+		}
+	}
+	if max < samples/20 {
+		t.Fatalf("most popular key got only %v requests", max)
+	}
+	if counts[0] == max {
+		t.Fatal("key 0 should not be the most popular one")
+	}
+}
+
+func TestHotspot(t *testing.T) {
+	n := uint64(1000)
+	samples := 100000
+	g := Hotspot(n, 0.1, 0.9)(1)
+	var hot int
+	for i := 0; i < samples; i++ {
+		if g.Next() < n/10 {
+			hot++
+		}
+	}
+	if share := float64(hot) / float64(samples); share < 0.88 || share > 0.92 {
+		t.Fatalf("hot keys got %v of the requests", share)
+	}
+}
+
+func TestSequential(t *testing.T) {
+	g := Sequential(3)(1)
+	for i, expected := range []uint64{1, 2, 0, 1} {
+		if k := g.Next(); k != expected {
+			t.Fatalf("%v: %v != %v", i, k, expected)
+		}
+	}
+}
+
+func TestRun(t *testing.T) {
+	results := Run(Config{
+		Builder:  gcache.New(1000).LRU(),
+		Workload: Workload{Name: "zipf", Keys: Zipf(10000, 0.99), ReadRatio: 0.9},
+		Ops:      20000,
+		Procs:    []int{1, 2},
+	})
+	if len(results) != 2 {
+		t.Fatalf("%v results, expected 2", len(results))
+	}
+	for i, r := range results {
+		if r.Procs != i+1 || r.Ops != 20000 {
+			t.Fatalf("unexpected result %v", r)
+		}
+		if r.HitRatio < 0.3 || r.HitRatio > 1 {
+			t.Fatalf("unexpected hit ratio %v", r.HitRatio)
+		}
+		if r.Throughput <= 0 || r.P50 > r.P99 || r.P99 > r.P999 || r.P999 > r.Max {
+			t.Fatalf("unexpected result %v", r)
+		}
+	}
+}
+
+// BenchmarkPolicies runs every workload against every eviction type.
+func BenchmarkPolicies(b *testing.B) {
+	types := []string{
+		gcache.TYPE_SIMPLE, gcache.TYPE_LRU, gcache.TYPE_LFU, gcache.TYPE_ARC,
+		gcache.TYPE_2Q, gcache.TYPE_SIEVE, gcache.TYPE_S3FIFO, gcache.TYPE_CLOCK,
+		gcache.TYPE_CLOCK_PRO, gcache.TYPE_LIRS, gcache.TYPE_GDSF, gcache.TYPE_ADAPTIVE,
+	}
+	for _, wl := range Workloads(100000) {
+		for _, tp := range types {
+			wl, tp := wl, tp
+			b.Run(fmt.Sprintf("%s/%s", wl.Name, tp), func(b *testing.B) {
+				gc := gcache.New(10000).EvictType(tp).Build()
+				b.ReportAllocs()
+				b.ResetTimer()
+				var seed int64
+				b.RunParallel(func(pb *testing.PB) {
+					s := newStream(wl, atomic.AddInt64(&seed, 1))
+					for pb.Next() {
+						key, read := s.next()
+						do(gc, key, read)
+					}
+				})
+				b.ReportMetric(100*gc.HitRate(), "hit%")
+			})
+		}
+	}
+}
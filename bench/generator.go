@@ -0,0 +1,165 @@
+package bench
+
+import (
+	"math"
+	"math/rand"
+)
+
+// Generator produces a stream of keys in [0, n).
+// Generators are not safe for concurrent use; every worker of Run gets its own.
+type Generator interface {
+	Next() uint64
+}
+
+// GeneratorFunc makes a generator from a seed, so that every worker gets an
+// independent but reproducible stream.
+type GeneratorFunc func(seed int64) Generator
+
+// Uniform returns keys in [0, n) with equal probability.
+func Uniform(n uint64) GeneratorFunc {
+	return func(seed int64) Generator {
+		return &uniform{rnd: rand.New(rand.NewSource(seed)), n: n}
+	}
+}
+
+type uniform struct {
+	rnd *rand.Rand
+	n   uint64
+}
+
+func (g *uniform) Next() uint64 {
+	return uint64(g.rnd.Int63n(int64(g.n)))
+}
+
+// Zipf returns keys in [0, n) following a Zipfian distribution with the
+// exponent theta in (0, 1), where key 0 is the most popular one. YCSB uses a
+// theta of 0.99.
+func Zipf(n uint64, theta float64) GeneratorFunc {
+	if theta <= 0 || theta >= 1 {
+		panic("bench: Zipf theta must be in (0, 1)")
+	}
+	zetan := zeta(n, theta)
+	return func(seed int64) Generator {
+		return newZipf(rand.New(rand.NewSource(seed)), n, theta, zetan)
+	}
+}
+
+// ScrambledZipf is like Zipf, but spreads the popular keys over the whole
+// key space instead of packing them at its start.
+func ScrambledZipf(n uint64, theta float64) GeneratorFunc {
+	zipf := Zipf(n, theta)
+	return func(seed int64) Generator {
+		return &scrambled{g: zipf(seed), n: n}
+	}
+}
+
+// zipf implements the algorithm of Gray et al., "Quickly Generating
+// Billion-Record Synthetic Databases", as used by YCSB.
+type zipf struct {
+	rnd   *rand.Rand
+	n     uint64
+	theta float64
+	alpha float64
+	zetan float64
+	eta   float64
+}
+
+func newZipf(rnd *rand.Rand, n uint64, theta, zetan float64) *zipf {
+	return &zipf{
+		rnd:   rnd,
+		n:     n,
+		theta: theta,
+		alpha: 1 / (1 - theta),
+		zetan: zetan,
+		eta:   (1 - math.Pow(2/float64(n), 1-theta)) / (1 - zeta(2, theta)/zetan),
+	}
+}
+
+func zeta(n uint64, theta float64) float64 {
+	var sum float64
+	for i := uint64(1); i <= n; i++ {
+		sum += 1 / math.Pow(float64(i), theta)
+	}
+	return sum
+}
+
+func (g *zipf) Next() uint64 {
+	u := g.rnd.Float64()
+	uz := u * g.zetan
+	if uz < 1 {
+		return 0
+	}
+	if uz < 1+math.Pow(0.5, g.theta) {
+		return 1
+	}
+	k := uint64(float64(g.n) * math.Pow(g.eta*u-g.eta+1, g.alpha))
+	if k >= g.n {
+		k = g.n - 1
+	}
+	return k
+}
+
+type scrambled struct {
+	g Generator
+	n uint64
+}
+
+func (g *scrambled) Next() uint64 {
+	return fnv64a(g.g.Next()) % g.n
+}
+
+// fnv64a hashes the bytes of k.
+func fnv64a(k uint64) uint64 {
+	h := uint64(14695981039346656037)
+	for i := 0; i < 8; i++ {
+		h ^= k & 0xff
+		h *= 1099511628211
+		k >>= 8
+	}
+	return h
+}
+
+// Hotspot returns keys in [0, n) where a fraction hotKeys of the key space
+// receives a fraction hotOps of the requests. Both fractions are in [0, 1].
+func Hotspot(n uint64, hotKeys, hotOps float64) GeneratorFunc {
+	hot := uint64(float64(n) * hotKeys)
+	if hot == 0 {
+		hot = 1
+	}
+	return func(seed int64) Generator {
+		return &hotspot{rnd: rand.New(rand.NewSource(seed)), n: n, hot: hot, hotOps: hotOps}
+	}
+}
+
+type hotspot struct {
+	rnd    *rand.Rand
+	n      uint64
+	hot    uint64
+	hotOps float64
+}
+
+func (g *hotspot) Next() uint64 {
+	if g.hot >= g.n || g.rnd.Float64() < g.hotOps {
+		return uint64(g.rnd.Int63n(int64(g.hot)))
+	}
+	return g.hot + uint64(g.rnd.Int63n(int64(g.n-g.hot)))
+}
+
+// Sequential scans the keys [0, n) in order over and over. Every stream
+// starts at a different key derived from its seed.
+func Sequential(n uint64) GeneratorFunc {
+	return func(seed int64) Generator {
+		return &sequential{n: n, next: uint64(seed) % n}
+	}
+}
+
+type sequential struct {
+	n    uint64
+	next uint64
+}
+
+func (g *sequential) Next() uint64 {
+	k := g.next
+	g.next = (g.next + 1) % g.n
+	return k
+}
@@ -0,0 +1,132 @@
+package gcache
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is returned instead of calling the loader while the circuit
+// breaker is open.
+var ErrCircuitOpen = errors.New("gcache: circuit breaker is open")
+
+// CircuitState is the state of the circuit breaker of a cache.
+type CircuitState int32
+
+const (
+	// CircuitClosed lets every load through.
+	CircuitClosed CircuitState = iota
+	// CircuitOpen fails loads without calling the loader.
+	CircuitOpen
+	// CircuitHalfOpen lets a single load through to probe the loader.
+	CircuitHalfOpen
+)
+
+func (s CircuitState) String() string {
+	switch s {
+	case CircuitClosed:
+		return "closed"
+	case CircuitOpen:
+		return "open"
+	case CircuitHalfOpen:
+		return "half-open"
+	}
+	return "unknown"
+}
+
+// CircuitStateFunc is called when the circuit breaker changes from one state
+// to another. It is called with the lock of the breaker held.
+type CircuitStateFunc func(from, to CircuitState)
+
+// circuitBreaker stops calling a failing loader. After a number of
+// consecutive failed loads it opens for a cooldown period, during which
+// loads fail with ErrCircuitOpen. Then a single load is let through: the
+// breaker closes if it succeeds and opens again if it fails.
+type circuitBreaker struct {
+	clock     Clock
+	threshold int
+	cooldown  time.Duration
+	onChange  CircuitStateFunc
+	*stats
+
+	mu       sync.Mutex
+	state    CircuitState
+	failures int
+	openedAt time.Time
+	probing  bool
+}
+
+func newCircuitBreaker(cb *CacheBuilder, st *stats) *circuitBreaker {
+	if cb.breakerThreshold <= 0 {
+		return nil
+	}
+	return &circuitBreaker{
+		clock:     cb.clock,
+		threshold: cb.breakerThreshold,
+		cooldown:  cb.breakerCooldown,
+		onChange:  cb.circuitStateFunc,
+		stats:     st,
+	}
+}
+
+// allow reports whether a load may call the loader. Every allowed load has
+// to be followed by a call to done with its outcome.
+func (b *circuitBreaker) allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch b.state {
+	case CircuitOpen:
+		if b.clock.Now().Sub(b.openedAt) < b.cooldown {
+			return false
+		}
+		b.setState(CircuitHalfOpen)
+		fallthrough
+	case CircuitHalfOpen:
+		if b.probing {
+			return false
+		}
+		b.probing = true
+	}
+	return true
+}
+
+// done records the outcome of an allowed load.
+func (b *circuitBreaker) done(succeeded bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state == CircuitHalfOpen {
+		b.probing = false
+	}
+	if succeeded {
+		b.failures = 0
+		b.setState(CircuitClosed)
+		return
+	}
+	b.failures++
+	if b.state == CircuitHalfOpen || b.failures >= b.threshold {
+		b.openedAt = b.clock.Now()
+		b.setState(CircuitOpen)
+	}
+}
+
+// isOpen reports whether loads currently fail because of the breaker.
+func (b *circuitBreaker) isOpen() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.state == CircuitOpen
+}
+
+func (b *circuitBreaker) setState(state CircuitState) {
+	from := b.state
+	if from == state {
+		return
+	}
+	b.state = state
+	b.stats.setCircuitState(state)
+	if state == CircuitOpen {
+		b.stats.IncrCircuitOpenCount()
+	}
+	if b.onChange != nil {
+		b.onChange(from, state)
+	}
+}
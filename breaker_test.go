@@ -0,0 +1,179 @@
+package gcache
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+var errLoader = errors.New("loader failed")
+
+func TestLoaderRetry(t *testing.T) {
+	var calls int
+	gc := New(10).
+		LRU().
+		LoaderRetry(3, time.Millisecond, 2*time.Millisecond).
+		LoaderFunc(func(key interface{}) (interface{}, error) {
+			calls++
+			if calls < 3 {
+				return nil, errLoader
+			}
+			return "ok", nil
+		}).
+		Build()
+	v, err := gc.Get("key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v != "ok" || calls != 3 {
+		t.Errorf("%v after %v calls", v, calls)
+	}
+	if n := gc.LoaderRetryCount(); n != 2 {
+		t.Errorf("%v retries, expected 2", n)
+	}
+
+	calls = -10
+	if _, err := gc.Get("other"); !errors.Is(err, errLoader) {
+		t.Errorf("%v != %v", err, errLoader)
+	}
+	if calls != -6 {
+		t.Errorf("%v calls, expected 4", calls+10)
+	}
+}
+
+func TestLoaderRetryWithFakeClock(t *testing.T) {
+	clock := NewFakeClock()
+	start := clock.Now()
+	calls := 0
+	gc := New(10).
+		LRU().
+		Clock(clock).
+		LoaderRetry(2, time.Hour, 0).
+		LoaderFunc(func(key interface{}) (interface{}, error) {
+			calls++
+			return nil, errLoader
+		}).
+		Build()
+	if _, err := gc.Get("key"); !errors.Is(err, errLoader) {
+		t.Fatalf("%v != %v", err, errLoader)
+	}
+	if calls != 3 {
+		t.Errorf("%v calls, expected 3", calls)
+	}
+	// the waits of an hour and two hours are halved at most
+	if d := clock.Now().Sub(start); d < 90*time.Minute || d > 3*time.Hour {
+		t.Errorf("the clock advanced by %v", d)
+	}
+}
+
+func TestLoaderBackoff(t *testing.T) {
+	c := &baseCache{loaderBackoff: 10 * time.Millisecond, loaderMaxBackoff: 50 * time.Millisecond}
+	for retry, max := range []time.Duration{10, 20, 40, 50, 50} {
+		max *= time.Millisecond
+		for i := 0; i < 100; i++ {
+			if d := c.backoff(retry); d < max/2 || d > max {
+				t.Fatalf("retry %v: %v not in [%v, %v]", retry, d, max/2, max)
+			}
+		}
+	}
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	clock := NewFakeClock()
+	var calls int
+	fail := true
+	var changes []CircuitState
+	gc := New(10).
+		LRU().
+		Clock(clock).
+		CircuitBreaker(3, time.Minute).
+		CircuitStateFunc(func(from, to CircuitState) {
+			changes = append(changes, to)
+		}).
+		LoaderFunc(func(key interface{}) (interface{}, error) {
+			calls++
+			if fail {
+				return nil, errLoader
+			}
+			return "ok", nil
+		}).
+		Build()
+
+	for i := 0; i < 3; i++ {
+		if _, err := gc.Get(i); !errors.Is(err, errLoader) {
+			t.Fatalf("%v != %v", err, errLoader)
+		}
+	}
+	if gc.CircuitState() != CircuitOpen {
+		t.Fatalf("the breaker is %v", gc.CircuitState())
+	}
+	if _, err := gc.Get("key"); err != ErrCircuitOpen {
+		t.Errorf("%v != %v", err, ErrCircuitOpen)
+	}
+	if calls != 3 {
+		t.Errorf("%v loader calls, expected 3", calls)
+	}
+
+	// a failed probe opens the breaker again
+	clock.Advance(time.Minute)
+	if _, err := gc.Get("key"); !errors.Is(err, errLoader) {
+		t.Errorf("%v != %v", err, errLoader)
+	}
+	if _, err := gc.Get("key"); err != ErrCircuitOpen {
+		t.Errorf("%v != %v", err, ErrCircuitOpen)
+	}
+
+	// a successful probe closes it
+	clock.Advance(time.Minute)
+	fail = false
+	if _, err := gc.Get("key"); err != nil {
+		t.Error(err)
+	}
+	expected := []CircuitState{CircuitOpen, CircuitHalfOpen, CircuitOpen, CircuitHalfOpen, CircuitClosed}
+	if !reflect.DeepEqual(changes, expected) {
+		t.Errorf("%v != %v", changes, expected)
+	}
+	if gc.CircuitState() != CircuitClosed || gc.CircuitOpenCount() != 2 {
+		t.Errorf("state %v after %v openings", gc.CircuitState(), gc.CircuitOpenCount())
+	}
+}
+
+func TestServeStale(t *testing.T) {
+	for _, serveStale := range []bool{false, true} {
+		clock := NewFakeClock()
+		fail := false
+		cb := New(10).
+			LRU().
+			Clock(clock).
+			Expiration(time.Second).
+			CircuitBreaker(1, time.Minute).
+			LoaderFunc(func(key interface{}) (interface{}, error) {
+				if fail {
+					return nil, errLoader
+				}
+				return "ok", nil
+			})
+		if serveStale {
+			cb.ServeStale()
+		}
+		gc := cb.Build()
+		if _, err := gc.Get("key"); err != nil {
+			t.Fatal(err)
+		}
+		clock.Advance(2 * time.Second)
+		fail = true
+		for i := 0; i < 2; i++ {
+			v, err := gc.Get("key")
+			if serveStale && (err != nil || v != "ok") {
+				t.Errorf("%v, %v: the stale value should be served", v, err)
+			}
+			if !serveStale && err == nil {
+				t.Errorf("%v: the stale value should not be served", v)
+			}
+		}
+		if gc.Has("key") {
+			t.Error("a stale entry should not be reported as present")
+		}
+	}
+}
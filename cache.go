@@ -2,34 +2,68 @@ package gcache
 
 import (
 	"errors"
-	"fmt"
+	"math"
+	"math/rand"
+	"runtime/debug"
 	"sync"
+	"sync/atomic"
 	"time"
+	"unsafe"
+
+	"github.com/bluele/gcache/singleflight"
 )
 
 const (
-	TYPE_SIMPLE = "simple"
-	TYPE_LRU    = "lru"
-	TYPE_LFU    = "lfu"
-	TYPE_ARC    = "arc"
+	TYPE_SIMPLE    = "simple"
+	TYPE_LRU       = "lru"
+	TYPE_LFU       = "lfu"
+	TYPE_ARC       = "arc"
+	TYPE_2Q        = "2q"
+	TYPE_SIEVE     = "sieve"
+	TYPE_S3FIFO    = "s3fifo"
+	TYPE_CLOCK     = "clock"
+	TYPE_CLOCK_PRO = "clockpro"
+	TYPE_LIRS      = "lirs"
+	TYPE_GDSF      = "gdsf"
+	TYPE_ADAPTIVE  = "adaptive"
 )
 
-var KeyNotFoundError = errors.New("Key not found.")
+var (
+	// ErrTooManyLoads is returned instead of calling the loader when
+	// MaxConcurrentLoads loads are in flight and RejectExcessLoads is set.
+	ErrTooManyLoads = errors.New("gcache: too many concurrent loads")
+	// ErrLoaderTimeout is returned by Get when a load takes longer than the
+	// LoaderTimeout.
+	ErrLoaderTimeout = errors.New("gcache: loader timed out")
+)
 
 type Cache interface {
 	// Set inserts or updates the specified key-value pair.
 	Set(key, value interface{}) error
 	// SetWithExpire inserts or updates the specified key-value pair with an expiration time.
 	SetWithExpire(key, value interface{}, expiration time.Duration) error
+	// SetWithSlidingExpire inserts or updates the specified key-value pair,
+	// which expires once it has not been read for the given time.
+	SetWithSlidingExpire(key, value interface{}, expiration time.Duration) error
 	// Get returns the value for the specified key if it is present in the cache.
 	// If the key is not present in the cache and the cache has LoaderFunc,
 	// invoke the `LoaderFunc` function and inserts the key-value pair in the cache.
 	// If the key is not present in the cache and the cache does not have a LoaderFunc,
-	// return KeyNotFoundError.
+	// return ErrKeyNotFound.
 	Get(key interface{}) (interface{}, error)
+	// GetAsync returns a Future of the value for the specified key, which
+	// is loaded in the background like Get would if the key is not present.
+	GetAsync(key interface{}) *Future
 	// GetIFPresent returns the value for the specified key if it is present in the cache.
-	// Return KeyNotFoundError if the key is not present.
+	// Return ErrKeyNotFound if the key is not present.
 	GetIFPresent(key interface{}) (interface{}, error)
+	// Peek returns the value for the specified key if it is present in the
+	// cache, without updating its recency or frequency, the statistics, or
+	// loading it. Return ErrKeyNotFound if the key is not present.
+	Peek(key interface{}) (interface{}, error)
+	// PeekWithExpiry returns the value like Peek, and the time left before
+	// it expires, or NeverExpire.
+	PeekWithExpiry(key interface{}) (interface{}, time.Duration, error)
 	// GetAll returns a map containing all key-value pairs in the cache.
 	GetALL(checkExpired bool) map[interface{}]interface{}
 	get(key interface{}, onLoad bool) (interface{}, error)
@@ -38,29 +72,84 @@ type Cache interface {
 	Remove(key interface{}) bool
 	// Purge removes all key-value pairs from the cache.
 	Purge()
+	// Ascend calls fn for the key-value pairs in the cache, from the next to
+	// be evicted to the last, until fn returns false.
+	Ascend(fn func(key, value interface{}) bool)
+	// Descend calls fn like Ascend, from the last to be evicted to the next.
+	Descend(fn func(key, value interface{}) bool)
 	// Keys returns a slice containing all keys in the cache.
 	Keys(checkExpired bool) []interface{}
 	// Len returns the number of items in the cache.
 	Len(checkExpired bool) int
 	// Has returns true if the key exists in the cache.
 	Has(key interface{}) bool
+	// GetEntry returns the metadata of the specified key if it is present
+	// in the cache, without the side effects of a read.
+	GetEntry(key interface{}) (Entry, bool)
 
 	statsAccessor
 }
 
+// baseCache holds the entries of a cache and everything shared by the
+// eviction policies: loading, expiration, callbacks and statistics. The
+// policy only decides which entry to evict.
 type baseCache struct {
-	clock            Clock
-	size             int
-	loaderExpireFunc LoaderExpireFunc
-	evictedFunc      EvictedFunc
-	purgeVisitorFunc PurgeVisitorFunc
-	addedFunc        AddedFunc
-	deserializeFunc  DeserializeFunc
-	serializeFunc    SerializeFunc
-	expiration       *time.Duration
-	mu               sync.RWMutex
-	loadGroup        Group
+	clock             Clock
+	size              int
+	loaderExpireFunc  LoaderExpireFunc
+	evictedFunc       EvictedFunc
+	purgeVisitorFunc  PurgeVisitorFunc
+	addedFunc         AddedFunc
+	deserializeFunc   DeserializeFunc
+	serializeFunc     SerializeFunc
+	expiration        *time.Duration
+	expireAfterAccess *time.Duration
+	expiry            Expiry
+	jitter            JitterFunc
+	earlyRefresh      float64
+	loaderRetries     int
+	loaderBackoff     time.Duration
+	loaderMaxBackoff  time.Duration
+	breaker           *circuitBreaker
+	serveStale        bool
+	loadSlots         chan struct{} // limits concurrent loads, if not nil
+	rejectExcessLoads bool
+	loaderTimeout     time.Duration
+	loaderPanicFunc   LoaderPanicFunc
+	repanic           bool
+	weigher           Weigher
+	costFunc          CostFunc
+	mu                sync.RWMutex
+	loadGroup         singleflight.Group
+	memory            *memoryController
 	*stats
+
+	policy       Policy
+	weighted     weightedPolicy // policy, if it takes weights and costs into account
+	ignoresReads bool           // the policy does nothing on OnAccess, so hits are not recorded
+	items        map[interface{}]*entry
+	weight       int // total weight of the entries
+	readPath
+}
+
+// entry is a cached key-value pair. Entries are never modified, except on
+// reads, which may push the deadline back and are counted: an update
+// replaces the entry, so that Get can use them without the lock.
+type entry struct {
+	deadline   int64     // UnixNano, 0 if the entry does not expire; accessed atomically
+	lastRead   int64     // UnixNano, 0 if the entry was not read; accessed atomically
+	reads      uint64    // accessed atomically
+	refreshing uint32    // 1 while an early refresh is running; accessed atomically
+	created    time.Time // time of the write
+	clock      Clock
+	key        interface{}
+	value      interface{}
+	expiration *time.Time    // deadline from the time of the write, which reads do not extend
+	idle       time.Duration // expire once not read for this long, if not 0
+	loadTime   time.Duration // time the loader took for the value, if it was loaded
+	weight     int
+	fromExpiry bool           // the Expiry decides the lifetime
+	mark       unsafe.Pointer // *accessMark of a markingPolicy; accessed atomically
 }
 
 type (
@@ -71,19 +160,50 @@ type (
 	AddedFunc        func(interface{}, interface{})
 	DeserializeFunc  func(interface{}, interface{}) (interface{}, error)
 	SerializeFunc    func(interface{}, interface{}) (interface{}, error)
+	Weigher          func(interface{}, interface{}) int
+	CostFunc         func(interface{}, interface{}, time.Duration) float64
 )
 
 type CacheBuilder struct {
-	clock            Clock
-	tp               string
-	size             int
-	loaderExpireFunc LoaderExpireFunc
-	evictedFunc      EvictedFunc
-	purgeVisitorFunc PurgeVisitorFunc
-	addedFunc        AddedFunc
-	expiration       *time.Duration
-	deserializeFunc  DeserializeFunc
-	serializeFunc    SerializeFunc
+	clock             Clock
+	tp                string
+	size              int
+	loaderExpireFunc  LoaderExpireFunc
+	evictedFunc       EvictedFunc
+	purgeVisitorFunc  PurgeVisitorFunc
+	addedFunc         AddedFunc
+	expiration        *time.Duration
+	expireAfterAccess *time.Duration
+	expiry            Expiry
+	jitter            JitterFunc
+	earlyRefresh      float64
+	deserializeFunc   DeserializeFunc
+	serializeFunc     SerializeFunc
+	weigher           Weigher
+	costFunc          CostFunc
+	policy            func() Policy
+
+	loaderRetries    int
+	loaderBackoff    time.Duration
+	loaderMaxBackoff time.Duration
+	breakerThreshold int
+	breakerCooldown  time.Duration
+	circuitStateFunc CircuitStateFunc
+	serveStale       bool
+
+	maxConcurrentLoads int
+	rejectExcessLoads  bool
+	loaderTimeout      time.Duration
+	loaderPanicFunc    LoaderPanicFunc
+	repanic            bool
+
+	lfuMaxFreq       uint
+	lfuAgingInterval time.Duration
+	lfuAgingSamples  int
+
+	memoryLimit         uint64
+	memoryCheckInterval time.Duration
+	memoryReader        MemoryReader
 }
 
 func New(size int) *CacheBuilder {
@@ -101,6 +221,8 @@ func (cb *CacheBuilder) Clock(clock Clock) *CacheBuilder {
 
 // Set a loader function.
 // loaderFunc: create a new value with this function if cached value is expired.
+// Errors of loaderFunc are returned by Get wrapped in a *LoaderError, so
+// that comparing them with == no longer matches: use errors.Is.
 func (cb *CacheBuilder) LoaderFunc(loaderFunc LoaderFunc) *CacheBuilder {
 	cb.loaderExpireFunc = func(k interface{}) (interface{}, *time.Duration, error) {
 		v, err := loaderFunc(k)
@@ -112,6 +234,8 @@ func (cb *CacheBuilder) LoaderFunc(loaderFunc LoaderFunc) *CacheBuilder {
 // Set a loader function with expiration.
 // loaderExpireFunc: create a new value with this function if cached value is expired.
 // If nil returned instead of time.Duration from loaderExpireFunc than value will never expire.
+// With an Expiry, a nil duration lets the Expiry decide instead.
+// Errors are wrapped in a *LoaderError like those of LoaderFunc.
 func (cb *CacheBuilder) LoaderExpireFunc(loaderExpireFunc LoaderExpireFunc) *CacheBuilder {
 	cb.loaderExpireFunc = loaderExpireFunc
 	return cb
@@ -134,10 +258,74 @@ func (cb *CacheBuilder) LFU() *CacheBuilder {
 	return cb.EvictType(TYPE_LFU)
 }
 
+// LFUMaxFrequency caps the access count of an item in the LFU policy, so that
+// a newly popular item can catch up with one that was used for a long time.
+// Zero means no cap.
+func (cb *CacheBuilder) LFUMaxFrequency(max uint) *CacheBuilder {
+	cb.lfuMaxFreq = max
+	return cb
+}
+
+// LFUAgingInterval makes the LFU policy halve the access counts of all items
+// each time interval has passed.
+func (cb *CacheBuilder) LFUAgingInterval(interval time.Duration) *CacheBuilder {
+	cb.lfuAgingInterval = interval
+	return cb
+}
+
+// LFUAgingSamples makes the LFU policy halve the access counts of all items
+// after every samples accesses.
+func (cb *CacheBuilder) LFUAgingSamples(samples int) *CacheBuilder {
+	cb.lfuAgingSamples = samples
+	return cb
+}
+
 func (cb *CacheBuilder) ARC() *CacheBuilder {
 	return cb.EvictType(TYPE_ARC)
 }
 
+func (cb *CacheBuilder) TwoQueue() *CacheBuilder {
+	return cb.EvictType(TYPE_2Q)
+}
+
+func (cb *CacheBuilder) Sieve() *CacheBuilder {
+	return cb.EvictType(TYPE_SIEVE)
+}
+
+func (cb *CacheBuilder) S3FIFO() *CacheBuilder {
+	return cb.EvictType(TYPE_S3FIFO)
+}
+
+func (cb *CacheBuilder) CLOCK() *CacheBuilder {
+	return cb.EvictType(TYPE_CLOCK)
+}
+
+func (cb *CacheBuilder) CLOCKPro() *CacheBuilder {
+	return cb.EvictType(TYPE_CLOCK_PRO)
+}
+
+func (cb *CacheBuilder) LIRS() *CacheBuilder {
+	return cb.EvictType(TYPE_LIRS)
+}
+
+func (cb *CacheBuilder) GDSF() *CacheBuilder {
+	return cb.EvictType(TYPE_GDSF)
+}
+
+// Adaptive makes the cache switch between LRU and LFU at runtime,
+// following whichever gets more hits on a sample of the keys.
+func (cb *CacheBuilder) Adaptive() *CacheBuilder {
+	return cb.EvictType(TYPE_ADAPTIVE)
+}
+
+// Policy makes the cache evict the entries chosen by a policy instead of
+// using one of the built-in eviction types. Every Build calls newPolicy, so
+// that caches from the same builder do not share a policy.
+func (cb *CacheBuilder) Policy(newPolicy func() Policy) *CacheBuilder {
+	cb.policy = newPolicy
+	return cb
+}
+
 func (cb *CacheBuilder) EvictedFunc(evictedFunc EvictedFunc) *CacheBuilder {
 	cb.evictedFunc = evictedFunc
 	return cb
@@ -168,6 +356,165 @@ func (cb *CacheBuilder) Expiration(expiration time.Duration) *CacheBuilder {
 	return cb
 }
 
+// ExpireAfterAccess makes entries expire once they have not been read for
+// the given time. Every successful Get pushes the deadline back. It can be
+// combined with Expiration, in which case entries expire at whichever
+// deadline comes first.
+func (cb *CacheBuilder) ExpireAfterAccess(expiration time.Duration) *CacheBuilder {
+	cb.expireAfterAccess = &expiration
+	return cb
+}
+
+// Expiry sets an Expiry that decides the lifetime of every entry on writes
+// and reads. It takes the place of Expiration and ExpireAfterAccess.
+func (cb *CacheBuilder) Expiry(expiry Expiry) *CacheBuilder {
+	cb.expiry = expiry
+	return cb
+}
+
+// ExpirationJitter spreads the expiration of entries written at the same
+// time, so that they do not all expire at once: every expiration is
+// shortened by a random share of up to fraction, such as 0.1 for up to 10%.
+// Sliding expirations and durations from an Expiry are not changed.
+func (cb *CacheBuilder) ExpirationJitter(fraction float64) *CacheBuilder {
+	return cb.ExpirationJitterFunc(RandomJitter(fraction, time.Now().UnixNano()))
+}
+
+// ExpirationJitterFunc sets the function that adjusts every expiration, in
+// place of ExpirationJitter. RandomJitter with a fixed seed gives
+// reproducible expirations.
+func (cb *CacheBuilder) ExpirationJitterFunc(jitter JitterFunc) *CacheBuilder {
+	cb.jitter = jitter
+	return cb
+}
+
+// EarlyRefresh makes Get reload entries of the loader shortly before they
+// expire, in the background, so that callers of hot keys do not all wait
+// for a load at the deadline. The closer the deadline and the longer the
+// last load took, the more likely a Get refreshes the entry. beta scales
+// how early refreshes happen: 1 is the usual choice, and larger values
+// refresh earlier. Entries without an expiration are never refreshed.
+func (cb *CacheBuilder) EarlyRefresh(beta float64) *CacheBuilder {
+	cb.earlyRefresh = beta
+	return cb
+}
+
+// LoaderRetry makes loads call a failing loader up to retries more times.
+// The first retry waits backoff, and every further one twice as long as
+// the previous one, up to maxBackoff. Every wait is randomized between half
+// and all of its duration. Callers of Get wait for the retries. The waits
+// go through the Clock if it has a Sleep method, like RealClock, and the
+// FakeClock, which advances instead; other clocks wait in wall time.
+func (cb *CacheBuilder) LoaderRetry(retries int, backoff, maxBackoff time.Duration) *CacheBuilder {
+	cb.loaderRetries = retries
+	cb.loaderBackoff = backoff
+	cb.loaderMaxBackoff = maxBackoff
+	return cb
+}
+
+// CircuitBreaker stops calling the loader after the given number of
+// consecutive failed loads, where a load counts once with all its retries.
+// Loads then fail with ErrCircuitOpen for the cooldown. After it, a single
+// load is let through, which closes the breaker if it succeeds.
+func (cb *CacheBuilder) CircuitBreaker(failures int, cooldown time.Duration) *CacheBuilder {
+	cb.breakerThreshold = failures
+	cb.breakerCooldown = cooldown
+	return cb
+}
+
+// CircuitStateFunc sets a function called on every state change of the
+// circuit breaker.
+func (cb *CacheBuilder) CircuitStateFunc(circuitStateFunc CircuitStateFunc) *CacheBuilder {
+	cb.circuitStateFunc = circuitStateFunc
+	return cb
+}
+
+// ServeStale keeps expired entries until they are replaced, and makes Get
+// return them instead of an error while the circuit breaker is open.
+// It requires a CircuitBreaker.
+func (cb *CacheBuilder) ServeStale() *CacheBuilder {
+	cb.serveStale = true
+	return cb
+}
+
+// MaxConcurrentLoads bounds the number of loader calls in flight. Loads of
+// further keys wait for a free slot, or fail with ErrTooManyLoads with
+// RejectExcessLoads. Loads of the same key are always merged into one.
+func (cb *CacheBuilder) MaxConcurrentLoads(n int) *CacheBuilder {
+	cb.maxConcurrentLoads = n
+	return cb
+}
+
+// RejectExcessLoads makes loads fail with ErrTooManyLoads instead of
+// waiting when MaxConcurrentLoads loads are in flight.
+func (cb *CacheBuilder) RejectExcessLoads() *CacheBuilder {
+	cb.rejectExcessLoads = true
+	return cb
+}
+
+// LoaderTimeout makes Get give up with ErrLoaderTimeout when a load takes
+// longer than timeout, including the wait for a slot of MaxConcurrentLoads.
+// The load goes on in the background and fills the cache when it completes.
+func (cb *CacheBuilder) LoaderTimeout(timeout time.Duration) *CacheBuilder {
+	cb.loaderTimeout = timeout
+	return cb
+}
+
+// LoaderPanicFunc sets a function called with every panic of the loader,
+// for example to log its stack.
+func (cb *CacheBuilder) LoaderPanicFunc(loaderPanicFunc LoaderPanicFunc) *CacheBuilder {
+	cb.loaderPanicFunc = loaderPanicFunc
+	return cb
+}
+
+// RepanicOnLoaderPanic makes Get panic with the *LoaderPanicError when the
+// loader panics, instead of returning it as an error. Every Get waiting for
+// the load panics. Loads in the background, such as those of GetIFPresent,
+// do not panic.
+func (cb *CacheBuilder) RepanicOnLoaderPanic() *CacheBuilder {
+	cb.repanic = true
+	return cb
+}
+
+// Weigher sets the function that returns the weight of a value.
+// The size of the cache then bounds the total weight of the items instead
+// of their number. The default weight is 1.
+func (cb *CacheBuilder) Weigher(weigher Weigher) *CacheBuilder {
+	cb.weigher = weigher
+	return cb
+}
+
+// Cost sets the function that returns how expensive a loaded value was to get.
+// It receives the key, the value and the time spent in the loader, and is
+// used by the GDSF policy. By default the cost is the load time in
+// milliseconds, and values added with Set have a cost of 1.
+func (cb *CacheBuilder) Cost(costFunc CostFunc) *CacheBuilder {
+	cb.costFunc = costFunc
+	return cb
+}
+
+// SoftMemoryLimit makes the cache watch heap usage and shrink itself,
+// evicting entries through its normal policy, while the heap is over limit bytes.
+// The capacity grows back towards the configured size once the pressure is gone.
+func (cb *CacheBuilder) SoftMemoryLimit(limit uint64) *CacheBuilder {
+	cb.memoryLimit = limit
+	return cb
+}
+
+// MemoryCheckInterval sets how often the heap is sampled when SoftMemoryLimit is set.
+// The check runs on writes, so an idle cache is never resized.
+func (cb *CacheBuilder) MemoryCheckInterval(interval time.Duration) *CacheBuilder {
+	cb.memoryCheckInterval = interval
+	return cb
+}
+
+// MemoryReader replaces the function used to sample heap usage.
+// By default it is read from runtime/metrics.
+func (cb *CacheBuilder) MemoryReader(reader MemoryReader) *CacheBuilder {
+	cb.memoryReader = reader
+	return cb
+}
+
 func (cb *CacheBuilder) Build() Cache {
 	if cb.size <= 0 && cb.tp != TYPE_SIMPLE {
 		panic("gcache: Cache size <= 0")
@@ -177,6 +524,9 @@ func (cb *CacheBuilder) Build() Cache {
 }
 
 func (cb *CacheBuilder) build() Cache {
+	if cb.policy != nil {
+		return newPolicyCache(cb)
+	}
 	switch cb.tp {
 	case TYPE_SIMPLE:
 		return newSimpleCache(cb)
@@ -186,36 +536,706 @@ func (cb *CacheBuilder) build() Cache {
 		return newLFUCache(cb)
 	case TYPE_ARC:
 		return newARC(cb)
+	case TYPE_2Q:
+		return newTwoQueueCache(cb)
+	case TYPE_SIEVE:
+		return newSieveCache(cb)
+	case TYPE_S3FIFO:
+		return newS3FIFOCache(cb)
+	case TYPE_CLOCK:
+		return newClockCache(cb)
+	case TYPE_CLOCK_PRO:
+		return newClockProCache(cb)
+	case TYPE_LIRS:
+		return newLIRSCache(cb)
+	case TYPE_GDSF:
+		return newGDSFCache(cb)
+	case TYPE_ADAPTIVE:
+		return newAdaptiveCache(cb)
 	default:
 		panic("gcache: Unknown type " + cb.tp)
 	}
 }
 
-func buildCache(c *baseCache, cb *CacheBuilder) {
+func buildCache(c *baseCache, cb *CacheBuilder, policy Policy) {
 	c.clock = cb.clock
 	c.size = cb.size
 	c.loaderExpireFunc = cb.loaderExpireFunc
 	c.expiration = cb.expiration
+	c.expireAfterAccess = cb.expireAfterAccess
+	c.expiry = cb.expiry
+	c.jitter = cb.jitter
+	c.earlyRefresh = cb.earlyRefresh
+	c.loaderRetries = cb.loaderRetries
+	c.loaderBackoff = cb.loaderBackoff
+	c.loaderMaxBackoff = cb.loaderMaxBackoff
+	if cb.maxConcurrentLoads > 0 {
+		c.loadSlots = make(chan struct{}, cb.maxConcurrentLoads)
+	}
+	c.rejectExcessLoads = cb.rejectExcessLoads
+	c.loaderTimeout = cb.loaderTimeout
+	c.loaderPanicFunc = cb.loaderPanicFunc
+	c.repanic = cb.repanic
 	c.addedFunc = cb.addedFunc
 	c.deserializeFunc = cb.deserializeFunc
 	c.serializeFunc = cb.serializeFunc
 	c.evictedFunc = cb.evictedFunc
 	c.purgeVisitorFunc = cb.purgeVisitorFunc
+	c.weigher = cb.weigher
+	c.costFunc = cb.costFunc
 	c.stats = &stats{}
+	c.stats.setCapacity(cb.size)
+	if cb.policy == nil {
+		c.stats.setActivePolicy(cb.tp)
+	}
+	c.memory = newMemoryController(cb, c.stats)
+	c.breaker = newCircuitBreaker(cb, c.stats)
+	c.serveStale = cb.serveStale && c.breaker != nil
+
+	c.policy = policy
+	c.weighted, _ = policy.(weightedPolicy)
+	_, c.ignoresReads = policy.(*simplePolicy)
+	if p, ok := policy.(SizedPolicy); ok {
+		p.SetSize(cb.size)
+	}
+	c.readPath.setup(&c.mu, c.applyRead)
+	c.init()
+}
+
+func (c *baseCache) init() {
+	if c.size <= 0 {
+		c.items = make(map[interface{}]*entry)
+	} else {
+		c.items = make(map[interface{}]*entry, c.size+1)
+	}
+	c.weight = 0
+	c.unpublishAll()
+}
+
+// noExpiration can be passed to set to give an entry no expiration even
+// if the cache has a default one.
+var noExpiration = new(time.Duration)
+
+// set inserts or replaces the entry of key. The entry expires after
+// expiration, and once it has not been read for idle. If both are nil, the
+// Expiry or the defaults of the cache decide. The cost is passed to weighted
+// policies, and loadTime is the time the loader took, or 0.
+func (c *baseCache) set(key, value interface{}, expiration, idle *time.Duration, cost float64, loadTime time.Duration) error {
+	var err error
+	if c.serializeFunc != nil {
+		value, err = c.serializeFunc(key, value)
+		if err != nil {
+			return &SerializeError{Key: key, Err: err}
+		}
+	}
+	c.flushReads()
+
+	e := &entry{
+		clock:    c.clock,
+		key:      key,
+		value:    value,
+		loadTime: loadTime,
+		weight:   1,
+	}
+	if c.weigher != nil {
+		e.weight = maxInt(1, c.weigher(key, value))
+	}
+	now := c.clock.Now()
+	e.created = now
+	if expiration == nil && idle == nil && c.expiry != nil {
+		var d time.Duration
+		if old, ok := c.items[key]; ok && old.fromExpiry {
+			d = c.expiry.ExpireAfterUpdate(key, value, old.remaining(now))
+		} else {
+			d = c.expiry.ExpireAfterCreate(key, value)
+		}
+		e.deadline = deadlineAfter(now, d)
+		e.fromExpiry = true
+	} else {
+		if expiration == nil {
+			expiration = c.expiration
+		}
+		if expiration != nil && expiration != noExpiration {
+			d := *expiration
+			if c.jitter != nil {
+				d = c.jitter(key, d)
+			}
+			t := now.Add(d)
+			e.expiration = &t
+			e.deadline = t.UnixNano()
+		}
+		if idle == nil {
+			idle = c.expireAfterAccess
+		}
+		if idle != nil && idle != noExpiration {
+			e.idle = *idle
+			e.touch(now)
+		}
+	}
+
+	// Check for existing item
+	if old, ok := c.items[key]; ok {
+		c.weight -= old.weight
+		c.putEntry(e)
+		if c.evict(0, e) {
+			c.policy.OnInsert(key)
+		} else if p, ok := c.policy.(UpdatePolicy); ok {
+			p.OnUpdate(key)
+		} else {
+			c.policy.OnAccess(key)
+		}
+		if c.weighted != nil {
+			c.weighted.setWeight(key, e.weight, cost)
+		}
+	} else {
+		if c.memory != nil {
+			c.memory.check(c.clock.Now(), c.size, c.resize)
+		}
+		// Verify size not exceeded
+		c.evict(e.weight, nil)
+		c.putEntry(e)
+		c.policy.OnInsert(key)
+		if c.weighted != nil {
+			c.weighted.setWeight(key, e.weight, cost)
+		}
+	}
+
+	if p, ok := c.policy.(markingPolicy); ok {
+		atomic.StorePointer(&e.mark, unsafe.Pointer(p.mark(key)))
+	}
+
+	if c.addedFunc != nil {
+		c.addedFunc(key, value)
+	}
+
+	return nil
+}
+
+func (c *baseCache) putEntry(e *entry) {
+	c.items[e.key] = e
+	c.weight += e.weight
+	c.publish(e)
+}
+
+// Set a new key-value pair
+func (c *baseCache) Set(key, value interface{}) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.set(key, value, nil, nil, 0, 0)
+}
+
+// Set a new key-value pair with an expiration time
+func (c *baseCache) SetWithExpire(key, value interface{}, expiration time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.set(key, value, &expiration, nil, 0, 0)
+}
+
+// SetWithSlidingExpire sets a new key-value pair that expires once it has
+// not been read for the given time, instead of the expirations configured
+// on the builder.
+func (c *baseCache) SetWithSlidingExpire(key, value interface{}, expiration time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.set(key, value, noExpiration, &expiration, 0, 0)
+}
+
+// Get a value from cache pool using key if it exists.
+// If it does not exists key and has LoaderFunc,
+// generate a value using `LoaderFunc` method returns value.
+func (c *baseCache) Get(key interface{}) (interface{}, error) {
+	v, err := c.get(key, false)
+	if err == ErrKeyNotFound {
+		return c.getWithLoader(key, true)
+	}
+	return v, err
+}
+
+// GetIFPresent gets a value from cache pool using key if it exists.
+// If it does not exists key, returns ErrKeyNotFound.
+// And send a request which refresh value for specified key if cache object has LoaderFunc.
+func (c *baseCache) GetIFPresent(key interface{}) (interface{}, error) {
+	v, err := c.get(key, false)
+	if err == ErrKeyNotFound {
+		return c.getWithLoader(key, false)
+	}
+	return v, err
+}
+
+// Peek gets a value from cache pool using key if it exists, without side
+// effects on the entry, the policy or the statistics.
+func (c *baseCache) Peek(key interface{}) (interface{}, error) {
+	v, _, err := c.PeekWithExpiry(key)
+	return v, err
+}
+
+// PeekWithExpiry gets a value like Peek, and the time left before it
+// expires.
+func (c *baseCache) PeekWithExpiry(key interface{}) (interface{}, time.Duration, error) {
+	e, ok := c.lookup(key)
+	if !ok {
+		return nil, 0, ErrKeyNotFound
+	}
+	now := c.clock.Now()
+	if e.IsExpired(&now) {
+		return nil, 0, ErrKeyNotFound
+	}
+	v, err := c.deserialize(key, e.value)
+	if err != nil {
+		return nil, 0, err
+	}
+	return v, e.remaining(now), nil
+}
+
+func (c *baseCache) get(key interface{}, onLoad bool) (interface{}, error) {
+	v, err := c.getValue(key, onLoad)
+	if err != nil {
+		return nil, err
+	}
+	return c.deserialize(key, v)
+}
+
+// deserialize returns the value of key stored as v.
+func (c *baseCache) deserialize(key, v interface{}) (interface{}, error) {
+	if c.deserializeFunc == nil {
+		return v, nil
+	}
+	v, err := c.deserializeFunc(key, v)
+	if err != nil {
+		return nil, &SerializeError{Key: key, Deserialize: true, Err: err}
+	}
+	return v, nil
+}
+
+func (c *baseCache) getValue(key interface{}, onLoad bool) (interface{}, error) {
+	e, ok := c.lookup(key)
+	if ok {
+		now := c.clock.Now()
+		if !e.IsExpired(&now) {
+			if e.idle > 0 {
+				e.touch(now)
+			} else if e.fromExpiry {
+				current := e.remaining(now)
+				if d := c.expiry.ExpireAfterRead(key, e.value, current); d != current {
+					atomic.StoreInt64(&e.deadline, deadlineAfter(now, d))
+				}
+			}
+			if m := (*accessMark)(atomic.LoadPointer(&e.mark)); m != nil {
+				// the policy only needs a mark, which is set at once
+				m.hit()
+			} else if !c.ignoresReads {
+				c.afterRead(e)
+			}
+			if !onLoad {
+				atomic.StoreInt64(&e.lastRead, now.UnixNano())
+				atomic.AddUint64(&e.reads, 1)
+				c.stats.IncrHitCount()
+				if c.earlyRefresh > 0 && c.loaderExpireFunc != nil {
+					c.refreshEarly(e, now)
+				}
+			}
+			return e.value, nil
+		}
+		if !c.serveStale {
+			c.removeExpired(e)
+		}
+	}
+	if !onLoad {
+		c.stats.IncrMissCount()
+	}
+	return nil, ErrKeyNotFound
+}
+
+func (c *baseCache) getWithLoader(key interface{}, isWait bool) (interface{}, error) {
+	if c.loaderExpireFunc == nil {
+		return nil, ErrKeyNotFound
+	}
+	value, _, err := c.load(key, func(v interface{}, expiration *time.Duration, elapsed time.Duration, e error) (interface{}, error) {
+		return c.fill(key, v, expiration, elapsed, e)
+	}, isWait)
+	if err != nil {
+		if perr, ok := err.(*LoaderPanicError); ok && c.repanic {
+			panic(perr)
+		}
+		if c.serveStale && c.breaker.isOpen() {
+			if e, ok := c.lookup(key); ok {
+				return c.deserialize(key, e.value)
+			}
+		}
+		return nil, err
+	}
+	return value, nil
+}
+
+// fill stores a value returned by the loader, which took elapsed to load it.
+func (c *baseCache) fill(key, v interface{}, expiration *time.Duration, elapsed time.Duration, e error) (interface{}, error) {
+	if e != nil {
+		return nil, e
+	}
+	var cost float64
+	if c.weighted != nil {
+		cost = c.loadCost(key, v, elapsed)
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.set(key, v, expiration, nil, cost, elapsed); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// refreshEarly reloads e in the background before it expires, with the
+// probability of XFetch: it grows as the deadline approaches and with the
+// time the last load took.
+// See Vattani et al., "Optimal Probabilistic Cache Stampede Prevention".
+func (c *baseCache) refreshEarly(e *entry, now time.Time) {
+	deadline := atomic.LoadInt64(&e.deadline)
+	if deadline == 0 || e.loadTime <= 0 {
+		return
+	}
+	gap := -float64(e.loadTime) * c.earlyRefresh * math.Log(rand.Float64())
+	if float64(now.UnixNano())+gap < float64(deadline) {
+		return
+	}
+	// only the first hit past the threshold starts a refresh
+	if !atomic.CompareAndSwapUint32(&e.refreshing, 0, 1) {
+		return
+	}
+	key := e.key
+	ch := c.loadGroup.DoChan(key, c.loadFunc(key, func(v interface{}, expiration *time.Duration, elapsed time.Duration, e error) (interface{}, error) {
+		return c.fill(key, v, expiration, elapsed, e)
+	}))
+	go func() {
+		// a successful refresh replaces the entry; after a failure the
+		// next hit may try again
+		if r := <-ch; r.Err != nil {
+			atomic.StoreUint32(&e.refreshing, 0)
+		}
+	}()
+}
+
+// loadCost returns how expensive the value of key was to load: the result of
+// the cost function, or the load time in milliseconds, and at least 1.
+func (c *baseCache) loadCost(key, value interface{}, elapsed time.Duration) float64 {
+	cost := float64(elapsed) / float64(time.Millisecond)
+	if c.costFunc != nil {
+		cost = c.costFunc(key, value, elapsed)
+	}
+	if cost < 1 {
+		cost = 1
+	}
+	return cost
+}
+
+// applyRead replays a buffered hit into the policy, unless the entry was
+// replaced or removed since.
+func (c *baseCache) applyRead(e *entry) {
+	if cur, ok := c.items[e.key]; ok && cur == e {
+		c.policy.OnAccess(e.key)
+	}
+}
+
+// removeExpired removes e if it is still cached and expired.
+func (c *baseCache) removeExpired(e *entry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if cur, ok := c.items[e.key]; ok && cur == e && e.IsExpired(nil) {
+		c.remove(e.key)
+	}
+}
+
+// evict removes the entries chosen by the policy until extra more weight
+// fits into the size of the cache. The entry keep is never removed; it
+// returns true if the policy gave it up anyway, in which case the caller
+// has to insert it into the policy again.
+func (c *baseCache) evict(extra int, keep *entry) bool {
+	kept := false
+	for c.size > 0 && c.weight+extra > c.size && len(c.items) > 0 {
+		key, ok := c.policy.Victim()
+		if !ok {
+			break
+		}
+		e, ok := c.items[key]
+		switch {
+		case !ok:
+		case e == keep:
+			kept = true
+		default:
+			c.removeEntry(e)
+		}
+	}
+	return kept
+}
+
+// resize changes the capacity, evicting the entries that no longer fit.
+func (c *baseCache) resize(size int) {
+	c.size = size
+	c.evict(0, nil)
+	if p, ok := c.policy.(SizedPolicy); ok {
+		p.SetSize(size)
+	}
+}
+
+// Has checks if key exists in cache
+func (c *baseCache) Has(key interface{}) bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	now := c.clock.Now()
+	return c.has(key, &now)
+}
+
+func (c *baseCache) has(key interface{}, now *time.Time) bool {
+	e, ok := c.items[key]
+	if !ok {
+		return false
+	}
+	return !e.IsExpired(now)
+}
+
+// Remove removes the provided key from the cache.
+func (c *baseCache) Remove(key interface{}) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.remove(key)
+}
+
+func (c *baseCache) remove(key interface{}) bool {
+	e, ok := c.items[key]
+	if !ok {
+		return false
+	}
+	c.policy.OnRemove(key)
+	c.removeEntry(e)
+	return true
+}
+
+// removeEntry drops e from the cache. The policy has to forget it separately.
+func (c *baseCache) removeEntry(e *entry) {
+	delete(c.items, e.key)
+	c.weight -= e.weight
+	c.unpublish(e.key)
+	if c.evictedFunc != nil {
+		c.evictedFunc(e.key, e.value)
+	}
+}
+
+// GetALL returns all key-value pairs in the cache.
+func (c *baseCache) GetALL(checkExpired bool) map[interface{}]interface{} {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	items := make(map[interface{}]interface{}, len(c.items))
+	now := c.clock.Now()
+	for k, e := range c.items {
+		if !checkExpired || c.has(k, &now) {
+			items[k] = e.value
+		}
+	}
+	return items
+}
+
+// Keys returns a slice of the keys in the cache.
+func (c *baseCache) Keys(checkExpired bool) []interface{} {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	keys := make([]interface{}, 0, len(c.items))
+	now := c.clock.Now()
+	for k := range c.items {
+		if !checkExpired || c.has(k, &now) {
+			keys = append(keys, k)
+		}
+	}
+	return keys
+}
+
+// Len returns the number of items in the cache.
+func (c *baseCache) Len(checkExpired bool) int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if !checkExpired {
+		return len(c.items)
+	}
+	var length int
+	now := c.clock.Now()
+	for k := range c.items {
+		if c.has(k, &now) {
+			length++
+		}
+	}
+	return length
+}
+
+// Completely clear the cache
+func (c *baseCache) Purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.purgeVisitorFunc != nil {
+		for key, e := range c.items {
+			c.purgeVisitorFunc(key, e.value)
+		}
+	}
+
+	if p, ok := c.policy.(resettablePolicy); ok {
+		p.reset()
+	} else {
+		for key := range c.items {
+			c.policy.OnRemove(key)
+		}
+	}
+	c.init()
 }
 
 // load a new value using by specified key.
-func (c *baseCache) load(key interface{}, cb func(interface{}, *time.Duration, error) (interface{}, error), isWait bool) (interface{}, bool, error) {
-	v, called, err := c.loadGroup.Do(key, func() (v interface{}, e error) {
-		defer func() {
-			if r := recover(); r != nil {
-				e = fmt.Errorf("Loader panics: %v", r)
+// cb also receives the time spent in the loader.
+func (c *baseCache) load(key interface{}, cb func(interface{}, *time.Duration, time.Duration, error) (interface{}, error), isWait bool) (interface{}, bool, error) {
+	loadFunc := c.loadFunc(key, cb)
+	fn := func() (interface{}, error) {
+		// another load may have filled the cache since the caller missed
+		if v, err := c.get(key, true); err == nil {
+			return v, nil
+		}
+		return loadFunc()
+	}
+	if !isWait {
+		c.loadGroup.DoChan(key, fn)
+		return nil, false, ErrKeyNotFound
+	}
+	if c.loaderTimeout > 0 {
+		return c.waitLoad(c.loadGroup.DoChan(key, fn))
+	}
+	v, err, shared := c.loadGroup.Do(key, fn)
+	if err != nil {
+		return nil, !shared, err
+	}
+	return v, !shared, nil
+}
+
+// waitLoad waits for the result of a load until the loader timeout, after
+// which the load goes on and fills the cache when it completes.
+func (c *baseCache) waitLoad(ch <-chan singleflight.Result) (interface{}, bool, error) {
+	timer := time.NewTimer(c.loaderTimeout)
+	defer timer.Stop()
+	select {
+	case r := <-ch:
+		if r.Err != nil {
+			return nil, !r.Shared, r.Err
+		}
+		return r.Val, !r.Shared, nil
+	case <-timer.C:
+		return nil, false, ErrLoaderTimeout
+	}
+}
+
+// loadFunc returns the function that calls the loader for key and passes
+// its results to cb.
+func (c *baseCache) loadFunc(key interface{}, cb func(interface{}, *time.Duration, time.Duration, error) (interface{}, error)) func() (interface{}, error) {
+	return func() (interface{}, error) {
+		start := c.clock.Now()
+		v, expiration, err := c.recoverLoader(key)
+		// panics of cb, such as in the SerializeFunc or AddedFunc, are not
+		// the loader's and propagate
+		return cb(v, expiration, c.clock.Now().Sub(start), err)
+	}
+}
+
+// recoverLoader calls callLoader and turns a panic of the loader into a
+// *LoaderPanicError.
+func (c *baseCache) recoverLoader(key interface{}) (v interface{}, expiration *time.Duration, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			perr := &LoaderPanicError{Key: key, Value: r, Stack: debug.Stack()}
+			if c.loaderPanicFunc != nil {
+				c.loaderPanicFunc(perr)
 			}
+			v, expiration, err = nil, nil, perr
+		}
+	}()
+	return c.callLoader(key)
+}
+
+// callLoader calls the loader, retries it after errors, and reports the
+// outcome to the circuit breaker.
+func (c *baseCache) callLoader(key interface{}) (v interface{}, expiration *time.Duration, err error) {
+	if c.loadSlots != nil {
+		if c.rejectExcessLoads {
+			select {
+			case c.loadSlots <- struct{}{}:
+			default:
+				return nil, nil, ErrTooManyLoads
+			}
+		} else {
+			c.loadSlots <- struct{}{}
+		}
+		defer func() { <-c.loadSlots }()
+	}
+	// a panicking loader counts as a failure
+	returned := false
+	if c.breaker != nil {
+		if !c.breaker.allow() {
+			return nil, nil, ErrCircuitOpen
+		}
+		defer func() {
+			c.breaker.done(returned && err == nil)
 		}()
-		return cb(c.loaderExpireFunc(key))
-	}, isWait)
+	}
+	v, expiration, err = c.loaderExpireFunc(key)
+	for i := 0; err != nil && i < c.loaderRetries; i++ {
+		sleep(c.clock, c.backoff(i))
+		c.stats.IncrLoaderRetryCount()
+		v, expiration, err = c.loaderExpireFunc(key)
+	}
+	returned = true
 	if err != nil {
-		return nil, called, err
+		return nil, nil, &LoaderError{Key: key, Err: err}
+	}
+	return v, expiration, nil
+}
+
+// backoff returns the randomized time to wait before the retry with the
+// given number, starting at 0.
+func (c *baseCache) backoff(retry int) time.Duration {
+	d := c.loaderBackoff
+	for i := 0; i < retry && (c.loaderMaxBackoff <= 0 || d < c.loaderMaxBackoff); i++ {
+		d *= 2
+	}
+	if c.loaderMaxBackoff > 0 && d > c.loaderMaxBackoff {
+		d = c.loaderMaxBackoff
+	}
+	if d <= 0 {
+		return 0
+	}
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+}
+
+// IsExpired returns boolean value whether this entry is expired or not.
+func (e *entry) IsExpired(now *time.Time) bool {
+	deadline := atomic.LoadInt64(&e.deadline)
+	if deadline == 0 {
+		return false
+	}
+	if now == nil {
+		t := e.clock.Now()
+		now = &t
+	}
+	return now.UnixNano() > deadline
+}
+
+// remaining returns the lifetime left to the entry at now.
+func (e *entry) remaining(now time.Time) time.Duration {
+	deadline := atomic.LoadInt64(&e.deadline)
+	if deadline == 0 {
+		return NeverExpire
+	}
+	return time.Duration(deadline - now.UnixNano())
+}
+
+// touch pushes the deadline of an entry with an idle time back after a
+// read at now, up to its write deadline.
+func (e *entry) touch(now time.Time) {
+	deadline := deadlineAfter(now, e.idle)
+	if e.expiration != nil && (deadline == 0 || e.expiration.UnixNano() < deadline) {
+		deadline = e.expiration.UnixNano()
 	}
-	return v, called, nil
+	atomic.StoreInt64(&e.deadline, deadline)
 }
@@ -16,6 +16,14 @@ func TestLoaderFunc(t *testing.T) {
 		New(size).LRU(),
 		New(size).LFU(),
 		New(size).ARC(),
+		New(size).TwoQueue(),
+		New(size).Sieve(),
+		New(size).S3FIFO(),
+		New(size).CLOCK(),
+		New(size).CLOCKPro(),
+		New(size).LIRS(),
+		New(size).GDSF(),
+		New(size).Adaptive(),
 	}
 	for _, builder := range testCaches {
 		var testCounter int64
@@ -55,6 +63,14 @@ func TestLoaderExpireFuncWithoutExpire(t *testing.T) {
 		New(size).LRU(),
 		New(size).LFU(),
 		New(size).ARC(),
+		New(size).TwoQueue(),
+		New(size).Sieve(),
+		New(size).S3FIFO(),
+		New(size).CLOCK(),
+		New(size).CLOCKPro(),
+		New(size).LIRS(),
+		New(size).GDSF(),
+		New(size).Adaptive(),
 	}
 	for _, builder := range testCaches {
 		var testCounter int64
@@ -94,6 +110,14 @@ func TestLoaderExpireFuncWithExpire(t *testing.T) {
 		New(size).LRU(),
 		New(size).LFU(),
 		New(size).ARC(),
+		New(size).TwoQueue(),
+		New(size).Sieve(),
+		New(size).S3FIFO(),
+		New(size).CLOCK(),
+		New(size).CLOCKPro(),
+		New(size).LIRS(),
+		New(size).GDSF(),
+		New(size).Adaptive(),
 	}
 	for _, builder := range testCaches {
 		var testCounter int64
@@ -158,6 +182,38 @@ func TestLoaderPurgeVisitorFunc(t *testing.T) {
 			name:         "arc",
 			cacheBuilder: New(size).ARC(),
 		},
+		{
+			name:         "2q",
+			cacheBuilder: New(size).TwoQueue(),
+		},
+		{
+			name:         "sieve",
+			cacheBuilder: New(size).Sieve(),
+		},
+		{
+			name:         "s3fifo",
+			cacheBuilder: New(size).S3FIFO(),
+		},
+		{
+			name:         "clock",
+			cacheBuilder: New(size).CLOCK(),
+		},
+		{
+			name:         "clockpro",
+			cacheBuilder: New(size).CLOCKPro(),
+		},
+		{
+			name:         "lirs",
+			cacheBuilder: New(size).LIRS(),
+		},
+		{
+			name:         "gdsf",
+			cacheBuilder: New(size).GDSF(),
+		},
+		{
+			name:         "adaptive",
+			cacheBuilder: New(size).Adaptive(),
+		},
 	}
 
 	for _, test := range tests {
@@ -213,6 +269,14 @@ func TestDeserializeFunc(t *testing.T) {
 		{TYPE_LRU},
 		{TYPE_LFU},
 		{TYPE_ARC},
+		{TYPE_2Q},
+		{TYPE_SIEVE},
+		{TYPE_S3FIFO},
+		{TYPE_CLOCK},
+		{TYPE_CLOCK_PRO},
+		{TYPE_LIRS},
+		{TYPE_GDSF},
+		{TYPE_ADAPTIVE},
 	}
 
 	for _, cs := range cases {
@@ -272,6 +336,14 @@ func TestExpiredItems(t *testing.T) {
 		TYPE_LRU,
 		TYPE_LFU,
 		TYPE_ARC,
+		TYPE_2Q,
+		TYPE_SIEVE,
+		TYPE_S3FIFO,
+		TYPE_CLOCK,
+		TYPE_CLOCK_PRO,
+		TYPE_LIRS,
+		TYPE_GDSF,
+		TYPE_ADAPTIVE,
 	}
 	for _, tp := range tps {
 		t.Run(tp, func(t *testing.T) {
@@ -279,3 +351,256 @@ func TestExpiredItems(t *testing.T) {
 		})
 	}
 }
+
+func TestExpireAfterAccess(t *testing.T) {
+	for _, tp := range []string{
+		TYPE_SIMPLE,
+		TYPE_LRU,
+		TYPE_LFU,
+		TYPE_ARC,
+		TYPE_2Q,
+		TYPE_SIEVE,
+		TYPE_S3FIFO,
+		TYPE_CLOCK,
+		TYPE_CLOCK_PRO,
+		TYPE_LIRS,
+		TYPE_GDSF,
+		TYPE_ADAPTIVE,
+	} {
+		t.Run(tp, func(t *testing.T) {
+			clock := NewFakeClock()
+			gc := New(10).
+				EvictType(tp).
+				Clock(clock).
+				ExpireAfterAccess(time.Minute).
+				Build()
+			gc.Set("active", 1)
+			gc.Set("idle", 2)
+			for i := 0; i < 5; i++ {
+				clock.Advance(50 * time.Second)
+				if _, err := gc.Get("active"); err != nil {
+					t.Fatalf("active key expired after %v reads: %v", i, err)
+				}
+			}
+			if gc.Has("idle") {
+				t.Error("idle key should have expired")
+			}
+			if _, err := gc.Get("idle"); err != KeyNotFoundError {
+				t.Errorf("idle key: %v", err)
+			}
+			if n := gc.Len(true); n != 1 {
+				t.Errorf("%v entries, expected 1", n)
+			}
+
+			gc.SetWithSlidingExpire("session", 3, time.Hour)
+			clock.Advance(59 * time.Minute)
+			if _, err := gc.Get("session"); err != nil {
+				t.Error(err)
+			}
+			clock.Advance(59 * time.Minute)
+			if !gc.Has("session") {
+				t.Error("session should still be cached")
+			}
+			clock.Advance(2 * time.Minute)
+			if _, err := gc.Get("session"); err != KeyNotFoundError {
+				t.Errorf("session: %v", err)
+			}
+		})
+	}
+}
+
+func TestExpireAfterAccessWithExpiration(t *testing.T) {
+	clock := NewFakeClock()
+	gc := New(10).
+		LRU().
+		Clock(clock).
+		Expiration(time.Hour).
+		ExpireAfterAccess(time.Minute).
+		Build()
+	gc.Set("key", 1)
+	for i := 0; i < 59; i++ {
+		clock.Advance(time.Minute)
+		if _, err := gc.Get("key"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// reads do not extend the write deadline
+	clock.Advance(2 * time.Minute)
+	if _, err := gc.Get("key"); err != KeyNotFoundError {
+		t.Errorf("key: %v", err)
+	}
+
+	// an explicit sliding expiration replaces both defaults
+	gc.SetWithSlidingExpire("key", 2, 2*time.Hour)
+	clock.Advance(90 * time.Minute)
+	if !gc.Has("key") {
+		t.Error("key should still be cached")
+	}
+}
+
+func TestMaxConcurrentLoads(t *testing.T) {
+	var inFlight, maxInFlight int32
+	release := make(chan struct{})
+	gc := New(100).
+		LRU().
+		MaxConcurrentLoads(2).
+		LoaderFunc(func(key interface{}) (interface{}, error) {
+			n := atomic.AddInt32(&inFlight, 1)
+			for {
+				max := atomic.LoadInt32(&maxInFlight)
+				if n <= max || atomic.CompareAndSwapInt32(&maxInFlight, max, n) {
+					break
+				}
+			}
+			<-release
+			atomic.AddInt32(&inFlight, -1)
+			return key, nil
+		}).
+		Build()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if v, err := gc.Get(i); err != nil || v != i {
+				t.Errorf("%v, %v", v, err)
+			}
+		}(i)
+	}
+	for i := 0; i < 10; i++ {
+		time.Sleep(time.Millisecond)
+		release <- struct{}{}
+	}
+	wg.Wait()
+	if max := atomic.LoadInt32(&maxInFlight); max != 2 {
+		t.Errorf("%v loads in flight, expected 2", max)
+	}
+}
+
+func TestRejectExcessLoads(t *testing.T) {
+	release := make(chan struct{})
+	started := make(chan struct{})
+	gc := New(100).
+		LRU().
+		MaxConcurrentLoads(1).
+		RejectExcessLoads().
+		LoaderFunc(func(key interface{}) (interface{}, error) {
+			if key == "slow" {
+				close(started)
+				<-release
+			}
+			return key, nil
+		}).
+		Build()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		if _, err := gc.Get("slow"); err != nil {
+			t.Error(err)
+		}
+	}()
+	<-started
+	if _, err := gc.Get("fast"); err != ErrTooManyLoads {
+		t.Errorf("%v != %v", err, ErrTooManyLoads)
+	}
+	close(release)
+	<-done
+	if _, err := gc.Get("fast"); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestLoaderTimeout(t *testing.T) {
+	release := make(chan struct{})
+	gc := New(10).
+		LRU().
+		LoaderTimeout(10 * time.Millisecond).
+		LoaderFunc(func(key interface{}) (interface{}, error) {
+			<-release
+			return "value", nil
+		}).
+		Build()
+	if _, err := gc.Get("key"); err != ErrLoaderTimeout {
+		t.Fatalf("%v != %v", err, ErrLoaderTimeout)
+	}
+	close(release)
+	// the load goes on and fills the cache
+	for i := 0; i < 100 && !gc.Has("key"); i++ {
+		time.Sleep(time.Millisecond)
+	}
+	if v, err := gc.GetIFPresent("key"); err != nil || v != "value" {
+		t.Errorf("%v, %v", v, err)
+	}
+}
+
+func TestPeek(t *testing.T) {
+	for _, tp := range []string{
+		TYPE_SIMPLE,
+		TYPE_LRU,
+		TYPE_LFU,
+		TYPE_ARC,
+		TYPE_2Q,
+		TYPE_SIEVE,
+		TYPE_S3FIFO,
+		TYPE_CLOCK,
+		TYPE_CLOCK_PRO,
+		TYPE_LIRS,
+		TYPE_GDSF,
+		TYPE_ADAPTIVE,
+	} {
+		t.Run(tp, func(t *testing.T) {
+			clock := NewFakeClock()
+			var loads int32
+			gc := New(10).
+				EvictType(tp).
+				Clock(clock).
+				LoaderFunc(func(key interface{}) (interface{}, error) {
+					atomic.AddInt32(&loads, 1)
+					return key, nil
+				}).
+				Build()
+			gc.SetWithExpire("key", "value", time.Minute)
+			gc.Set("forever", "value")
+			clock.Advance(time.Second)
+
+			if v, ttl, err := gc.PeekWithExpiry("key"); err != nil || v != "value" || ttl != 59*time.Second {
+				t.Errorf("unexpected %v, %v, %v", v, ttl, err)
+			}
+			if v, ttl, err := gc.PeekWithExpiry("forever"); err != nil || v != "value" || ttl != NeverExpire {
+				t.Errorf("unexpected %v, %v, %v", v, ttl, err)
+			}
+			if _, err := gc.Peek("missing"); err != ErrKeyNotFound {
+				t.Errorf("unexpected error %v", err)
+			}
+			clock.Advance(time.Minute)
+			if _, err := gc.Peek("key"); err != ErrKeyNotFound {
+				t.Errorf("unexpected error %v", err)
+			}
+			if n := gc.LookupCount(); n != 0 {
+				t.Errorf("%v lookups, expected 0", n)
+			}
+			time.Sleep(2 * time.Millisecond)
+			if n := atomic.LoadInt32(&loads); n != 0 {
+				t.Errorf("%v loads, expected 0", n)
+			}
+		})
+	}
+}
+
+func TestPeekDoesNotPromote(t *testing.T) {
+	gc := New(2).LRU().Build()
+	gc.Set(1, 1)
+	gc.Set(2, 2)
+	if v, err := gc.Peek(1); err != nil || v != 1 {
+		t.Errorf("unexpected %v, %v", v, err)
+	}
+	gc.Set(3, 3)
+	if gc.Has(1) {
+		t.Error("the peeked key is not evicted")
+	}
+	if !gc.Has(2) {
+		t.Error("the newer key is evicted")
+	}
+}
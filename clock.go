@@ -20,6 +20,25 @@ func (rc RealClock) Now() time.Time {
 	return t
 }
 
+func (rc RealClock) Sleep(d time.Duration) {
+	time.Sleep(d)
+}
+
+// sleeper is implemented by clocks that can wait, such as the backoff
+// between retries of the loader.
+type sleeper interface {
+	Sleep(d time.Duration)
+}
+
+// sleep waits d through clock, or in wall time if clock cannot wait.
+func sleep(clock Clock, d time.Duration) {
+	if s, ok := clock.(sleeper); ok {
+		s.Sleep(d)
+	} else {
+		time.Sleep(d)
+	}
+}
+
 type FakeClock interface {
 	Clock
 
@@ -51,3 +70,8 @@ func (fc *fakeclock) Advance(d time.Duration) {
 	defer fc.mutex.Unlock()
 	fc.now = fc.now.Add(d)
 }
+
+// Sleep advances the clock by d instead of waiting.
+func (fc *fakeclock) Sleep(d time.Duration) {
+	fc.Advance(d)
+}
@@ -0,0 +1,128 @@
+package gcache
+
+// ClockCache implements the CLOCK algorithm, an approximation of LRU.
+// Items live in a circular buffer with one reference bit per slot. A hit
+// only sets the bit. On eviction the hand sweeps the buffer, clearing set
+// bits, and evicts the first item whose bit is already clear.
+type ClockCache struct {
+	baseCache
+}
+
+var _ Cache = (*ClockCache)(nil)
+
+func newClockCache(cb *CacheBuilder) *ClockCache {
+	c := &ClockCache{}
+	buildCache(&c.baseCache, cb, newClockPolicy())
+	return c
+}
+
+type clockPolicy struct {
+	items map[interface{}]int // slot of each key
+	slots []*clockSlot
+	free  []int // empty slots
+	hand  int
+}
+
+type clockSlot struct {
+	key        interface{}
+	referenced accessMark
+}
+
+func newClockPolicy() *clockPolicy {
+	p := &clockPolicy{}
+	p.reset()
+	return p
+}
+
+func (p *clockPolicy) reset() {
+	p.items = make(map[interface{}]int, len(p.slots))
+	p.slots = make([]*clockSlot, len(p.slots))
+	p.free = make([]int, len(p.slots))
+	for i := range p.free {
+		// hand out the slots in order
+		p.free[i] = len(p.slots) - 1 - i
+	}
+	p.hand = 0
+}
+
+// SetSize moves the keys into a buffer of the new size, keeping their order
+// relative to the hand and their reference bits. The cache evicts the keys
+// that no longer fit before calling it.
+func (p *clockPolicy) SetSize(size int) {
+	size = maxInt(size, len(p.items))
+	slots := make([]*clockSlot, size)
+	n := 0
+	for i := 0; i < len(p.slots); i++ {
+		slot := (p.hand + i) % len(p.slots)
+		if s := p.slots[slot]; s != nil {
+			slots[n] = s
+			p.items[s.key] = n
+			n++
+		}
+	}
+	free := make([]int, 0, size-n)
+	for i := size - 1; i >= n; i-- {
+		free = append(free, i)
+	}
+	p.slots = slots
+	p.free = free
+	p.hand = 0
+}
+
+func (p *clockPolicy) OnInsert(key interface{}) {
+	if len(p.free) == 0 {
+		p.SetSize(len(p.slots) + 1)
+	}
+	slot := p.free[len(p.free)-1]
+	p.free = p.free[:len(p.free)-1]
+	p.slots[slot] = &clockSlot{key: key, referenced: accessMark{max: 1}}
+	p.items[key] = slot
+}
+
+func (p *clockPolicy) OnAccess(key interface{}) {
+	if slot, ok := p.items[key]; ok {
+		p.slots[slot].referenced.hit()
+	}
+}
+
+func (p *clockPolicy) mark(key interface{}) *accessMark {
+	if slot, ok := p.items[key]; ok {
+		return &p.slots[slot].referenced
+	}
+	return nil
+}
+
+func (p *clockPolicy) OnRemove(key interface{}) {
+	if slot, ok := p.items[key]; ok {
+		p.removeSlot(slot)
+	}
+}
+
+// Victim sweeps the hand over the buffer and returns the first key whose
+// reference bit is clear, clearing the bits that are set on the way.
+func (p *clockPolicy) Victim() (interface{}, bool) {
+	if len(p.items) == 0 {
+		return nil, false
+	}
+	for {
+		slot := p.hand
+		p.hand = (p.hand + 1) % len(p.slots)
+		s := p.slots[slot]
+		if s == nil {
+			continue
+		}
+		if s.referenced.count() > 0 {
+			s.referenced.clear()
+			continue
+		}
+		p.removeSlot(slot)
+		return s.key, true
+	}
+}
+
+// removeSlot empties the given slot and makes it available for reuse.
+func (p *clockPolicy) removeSlot(slot int) {
+	delete(p.items, p.slots[slot].key)
+	p.slots[slot] = nil
+	p.free = append(p.free, slot)
+}
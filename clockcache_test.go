@@ -0,0 +1,163 @@
+package gcache
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestClockGet(t *testing.T) {
+	size := 1000
+	gc := buildTestCache(t, TYPE_CLOCK, size)
+	testSetCache(t, gc, size)
+	testGetCache(t, gc, size)
+}
+
+func TestLoadingClockGet(t *testing.T) {
+	size := 1000
+	numbers := 1000
+	testGetCache(t, buildTestLoadingCache(t, TYPE_CLOCK, size, loader), numbers)
+}
+
+func TestClockLength(t *testing.T) {
+	clock := NewFakeClock()
+	gc := buildTestLoadingCacheWithClock(t, TYPE_CLOCK, 2, time.Millisecond, clock)
+	gc.Get("test1")
+	gc.Get("test2")
+	gc.Get("test3")
+	length := gc.Len(true)
+	expectedLength := 2
+	if length != expectedLength {
+		t.Errorf("Expected length is %v, not %v", expectedLength, length)
+	}
+	clock.Advance(2 * time.Millisecond)
+	gc.Get("test4")
+	length = gc.Len(true)
+	expectedLength = 1
+	if length != expectedLength {
+		t.Errorf("Expected length is %v, not %v", expectedLength, length)
+	}
+}
+
+func TestClockEvictItem(t *testing.T) {
+	cacheSize := 10
+	numbers := cacheSize + 1
+	gc := buildTestLoadingCache(t, TYPE_CLOCK, cacheSize, loader)
+
+	for i := 0; i < numbers; i++ {
+		_, err := gc.Get(fmt.Sprintf("Key-%d", i))
+		if err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+	}
+	if l := gc.Len(false); l != cacheSize {
+		t.Errorf("Expected length is %v, not %v", cacheSize, l)
+	}
+}
+
+func TestClockPurgeCache(t *testing.T) {
+	cacheSize := 10
+	purgeCount := 0
+	gc := New(cacheSize).
+		CLOCK().
+		LoaderFunc(loader).
+		PurgeVisitorFunc(func(k, v interface{}) {
+			purgeCount++
+		}).
+		Build()
+
+	for i := 0; i < cacheSize; i++ {
+		_, err := gc.Get(fmt.Sprintf("Key-%d", i))
+		if err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+	}
+
+	gc.Purge()
+
+	if purgeCount != cacheSize {
+		t.Errorf("failed to purge everything")
+	}
+}
+
+func TestClockGetIFPresent(t *testing.T) {
+	testGetIFPresent(t, TYPE_CLOCK)
+}
+
+func TestClockHas(t *testing.T) {
+	gc := buildTestLoadingCacheWithExpiration(t, TYPE_CLOCK, 2, 10*time.Millisecond)
+
+	for i := 0; i < 10; i++ {
+		t.Run(fmt.Sprint(i), func(t *testing.T) {
+			gc.Get("test1")
+			gc.Get("test2")
+
+			if gc.Has("test0") {
+				t.Fatal("should not have test0")
+			}
+			if !gc.Has("test1") {
+				t.Fatal("should have test1")
+			}
+			if !gc.Has("test2") {
+				t.Fatal("should have test2")
+			}
+
+			time.Sleep(20 * time.Millisecond)
+
+			if gc.Has("test0") {
+				t.Fatal("should not have test0")
+			}
+			if gc.Has("test1") {
+				t.Fatal("should not have test1")
+			}
+			if gc.Has("test2") {
+				t.Fatal("should not have test2")
+			}
+		})
+	}
+}
+
+func TestClockSecondChance(t *testing.T) {
+	size := 4
+	gc := buildTestCache(t, TYPE_CLOCK, size)
+
+	setItemsByRange(t, gc, 0, size)
+	gc.Get(0)
+	gc.Get(1)
+	// the hand clears the bits of 0 and 1 and evicts 2
+	setItemsByRange(t, gc, size, size+1)
+	if gc.Has(2) {
+		t.Fatal("2 should be evicted")
+	}
+	for _, key := range []int{0, 1, 3, 4} {
+		if !gc.Has(key) {
+			t.Fatalf("%v should be cached", key)
+		}
+	}
+	// then it moves on to 3
+	setItemsByRange(t, gc, size+1, size+2)
+	if gc.Has(3) {
+		t.Fatal("3 should be evicted")
+	}
+}
+
+func TestClockRemoveReusesSlot(t *testing.T) {
+	size := 4
+	gc := buildTestCache(t, TYPE_CLOCK, size).(*ClockCache)
+
+	setItemsByRange(t, gc, 0, size)
+	gc.Remove(1)
+	gc.Set(size, size)
+	if slot := gc.policy.(*clockPolicy).items[size]; slot != 1 {
+		t.Fatalf("%v should reuse slot 1, got %v", size, slot)
+	}
+	for i := 0; i <= size; i++ {
+		if i != 1 && !gc.Has(i) {
+			t.Fatalf("%v should be cached", i)
+		}
+	}
+}
+
+func TestClockConcurrentGet(t *testing.T) {
+	testConcurrentGet(t, TYPE_CLOCK)
+}
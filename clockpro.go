@@ -0,0 +1,296 @@
+package gcache
+
+import (
+	"container/ring"
+)
+
+const (
+	clockProHot = iota
+	clockProCold
+	clockProNonResident // evicted cold page still in its test period
+)
+
+// ClockProCache implements the CLOCK-Pro algorithm.
+// Resident pages are either hot or cold, and every page sits on a single
+// circular list together with the evicted cold pages whose test period has
+// not ended yet. A new page starts cold and in its test period. The cold
+// hand evicts unreferenced cold pages and promotes cold pages referenced
+// during their test period, the hot hand demotes unreferenced hot pages, and
+// the test hand ends test periods. A reuse within the test period gives cold
+// pages more room, while a test period ending without one gives them less.
+// A hit only sets a reference bit.
+type ClockProCache struct {
+	baseCache
+}
+
+var _ Cache = (*ClockProCache)(nil)
+
+func newClockProCache(cb *CacheBuilder) *ClockProCache {
+	c := &ClockProCache{}
+	buildCache(&c.baseCache, cb, newClockProPolicy())
+	return c
+}
+
+type clockProPolicy struct {
+	items map[interface{}]*ring.Ring // resident and test pages
+
+	handHot  *ring.Ring
+	handCold *ring.Ring
+	handTest *ring.Ring
+
+	size      int
+	countHot  int
+	countCold int
+	countTest int
+	coldSize  int // target number of resident cold pages
+}
+
+type clockProItem struct {
+	key  interface{}
+	kind int
+	test bool // in its test period
+	ref  accessMark
+}
+
+func newClockProPolicy() *clockProPolicy {
+	p := &clockProPolicy{}
+	p.reset()
+	return p
+}
+
+func (p *clockProPolicy) reset() {
+	p.items = make(map[interface{}]*ring.Ring)
+	p.handHot = nil
+	p.handCold = nil
+	p.handTest = nil
+	p.countHot = 0
+	p.countCold = 0
+	p.countTest = 0
+	// start balanced and let the test periods move the target
+	p.coldSize = maxInt(1, p.size/2)
+}
+
+// SetSize changes the capacity, running the test hand until the
+// non-resident pages fit.
+func (p *clockProPolicy) SetSize(size int) {
+	if p.size == 0 {
+		// first call: start balanced
+		p.coldSize = maxInt(1, size/2)
+	}
+	p.size = size
+	p.coldSize = minInt(p.coldSize, size)
+	for p.countTest > size {
+		p.runHandTest()
+	}
+	p.balanceHot()
+}
+
+func (p *clockProPolicy) OnInsert(key interface{}) {
+	if r, ok := p.items[key]; ok {
+		// A reuse within the test period: cold pages deserve more room,
+		// and the page comes back as a hot one.
+		if p.coldSize < p.size {
+			p.coldSize++
+		}
+		item := r.Value.(*clockProItem)
+		item.kind = clockProHot
+		item.test = false
+		item.ref.clear()
+		p.countTest--
+		p.unlink(r)
+		p.link(r)
+		p.countHot++
+		p.balanceHot()
+		return
+	}
+	p.link(&ring.Ring{Value: &clockProItem{
+		key:  key,
+		kind: clockProCold,
+		test: true,
+		ref:  accessMark{max: 1},
+	}})
+	p.countCold++
+}
+
+func (p *clockProPolicy) OnAccess(key interface{}) {
+	if r, ok := p.items[key]; ok {
+		r.Value.(*clockProItem).ref.hit()
+	}
+}
+
+func (p *clockProPolicy) mark(key interface{}) *accessMark {
+	if r, ok := p.items[key]; ok {
+		return &r.Value.(*clockProItem).ref
+	}
+	return nil
+}
+
+func (p *clockProPolicy) OnRemove(key interface{}) {
+	if r, ok := p.items[key]; ok && r.Value.(*clockProItem).kind != clockProNonResident {
+		p.removePage(r)
+	}
+}
+
+// Victim runs the cold hand until it evicts a resident cold page.
+func (p *clockProPolicy) Victim() (interface{}, bool) {
+	if p.countHot+p.countCold == 0 {
+		return nil, false
+	}
+	return p.runHandCold(), true
+}
+
+// link inserts r at the head of the list, right behind the hot hand.
+func (p *clockProPolicy) link(r *ring.Ring) {
+	p.items[r.Value.(*clockProItem).key] = r
+	if p.handHot == nil {
+		p.handHot = r
+		p.handCold = r
+		p.handTest = r
+	} else {
+		p.handHot.Prev().Link(r)
+	}
+}
+
+// unlink takes r off the list. A hand pointing at r steps back, so that it
+// moves on to the page that followed r.
+func (p *clockProPolicy) unlink(r *ring.Ring) {
+	delete(p.items, r.Value.(*clockProItem).key)
+	if r.Next() == r {
+		p.handHot = nil
+		p.handCold = nil
+		p.handTest = nil
+		return
+	}
+	if r == p.handHot {
+		p.handHot = r.Prev()
+	}
+	if r == p.handCold {
+		p.handCold = r.Prev()
+	}
+	if r == p.handTest {
+		p.handTest = r.Prev()
+	}
+	r.Prev().Unlink(1)
+}
+
+// runHandCold moves the cold hand until it evicts a resident cold page.
+// A referenced cold page is promoted to hot if it is in its test period and
+// starts a new test period otherwise. An evicted page in its test period
+// stays on the list as a non-resident page. It returns the evicted key.
+func (p *clockProPolicy) runHandCold() interface{} {
+	for {
+		if p.countCold == 0 {
+			p.runHandHot()
+			continue
+		}
+		r := p.handCold
+		p.handCold = r.Next()
+		item := r.Value.(*clockProItem)
+		if item.kind != clockProCold {
+			continue
+		}
+		if item.ref.count() > 0 {
+			item.ref.clear()
+			if item.test {
+				item.kind = clockProHot
+				item.test = false
+				p.countCold--
+				p.countHot++
+				p.balanceHot()
+			} else {
+				item.test = true
+			}
+			continue
+		}
+
+		p.countCold--
+		if !item.test {
+			p.unlink(r)
+			return item.key
+		}
+		item.kind = clockProNonResident
+		p.countTest++
+		for p.countTest > p.size {
+			p.runHandTest()
+		}
+		return item.key
+	}
+}
+
+// balanceHot runs the hot hand until the hot pages fit into the room left
+// by the target number of cold pages.
+func (p *clockProPolicy) balanceHot() {
+	for p.countHot > 0 && p.countHot > p.size-p.coldSize {
+		p.runHandHot()
+	}
+}
+
+// runHandHot moves the hot hand until it demotes a hot page that was not
+// referenced since the hand last passed. The test periods of the cold pages
+// it passes end on the way.
+func (p *clockProPolicy) runHandHot() {
+	for {
+		r := p.handHot
+		p.handHot = r.Next()
+		item := r.Value.(*clockProItem)
+		switch item.kind {
+		case clockProHot:
+			if item.ref.count() > 0 {
+				item.ref.clear()
+				continue
+			}
+			item.kind = clockProCold
+			p.countHot--
+			p.countCold++
+			return
+		case clockProCold:
+			if item.test {
+				p.endTestPeriod(item)
+			}
+		case clockProNonResident:
+			p.endTestPeriod(item)
+			p.unlink(r)
+			p.countTest--
+		}
+	}
+}
+
+// runHandTest moves the test hand until it removes a non-resident page,
+// ending the test periods of the cold pages it passes.
+func (p *clockProPolicy) runHandTest() {
+	for {
+		r := p.handTest
+		p.handTest = r.Next()
+		item := r.Value.(*clockProItem)
+		switch {
+		case item.kind == clockProCold && item.test:
+			p.endTestPeriod(item)
+		case item.kind == clockProNonResident:
+			p.endTestPeriod(item)
+			p.unlink(r)
+			p.countTest--
+			return
+		}
+	}
+}
+
+// endTestPeriod ends the test period of an item that was not reused in
+// time, so cold pages get less room.
+func (p *clockProPolicy) endTestPeriod(item *clockProItem) {
+	item.test = false
+	if p.coldSize > 1 {
+		p.coldSize--
+	}
+}
+
+// removePage drops a resident page without leaving a non-resident page
+// behind.
+func (p *clockProPolicy) removePage(r *ring.Ring) {
+	item := r.Value.(*clockProItem)
+	p.unlink(r)
+	if item.kind == clockProHot {
+		p.countHot--
+	} else {
+		p.countCold--
+	}
+}
@@ -0,0 +1,214 @@
+package gcache
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestClockProGet(t *testing.T) {
+	size := 1000
+	gc := buildTestCache(t, TYPE_CLOCK_PRO, size)
+	testSetCache(t, gc, size)
+	testGetCache(t, gc, size)
+}
+
+func TestLoadingClockProGet(t *testing.T) {
+	size := 1000
+	numbers := 1000
+	testGetCache(t, buildTestLoadingCache(t, TYPE_CLOCK_PRO, size, loader), numbers)
+}
+
+func TestClockProLength(t *testing.T) {
+	clock := NewFakeClock()
+	gc := buildTestLoadingCacheWithClock(t, TYPE_CLOCK_PRO, 2, time.Millisecond, clock)
+	gc.Get("test1")
+	gc.Get("test2")
+	gc.Get("test3")
+	length := gc.Len(true)
+	expectedLength := 2
+	if length != expectedLength {
+		t.Errorf("Expected length is %v, not %v", expectedLength, length)
+	}
+	clock.Advance(2 * time.Millisecond)
+	gc.Get("test4")
+	length = gc.Len(true)
+	expectedLength = 1
+	if length != expectedLength {
+		t.Errorf("Expected length is %v, not %v", expectedLength, length)
+	}
+}
+
+func TestClockProEvictItem(t *testing.T) {
+	cacheSize := 10
+	numbers := cacheSize + 1
+	gc := buildTestLoadingCache(t, TYPE_CLOCK_PRO, cacheSize, loader)
+
+	for i := 0; i < numbers; i++ {
+		_, err := gc.Get(fmt.Sprintf("Key-%d", i))
+		if err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+	}
+	if l := gc.Len(false); l != cacheSize {
+		t.Errorf("Expected length is %v, not %v", cacheSize, l)
+	}
+}
+
+func TestClockProPurgeCache(t *testing.T) {
+	cacheSize := 10
+	purgeCount := 0
+	gc := New(cacheSize).
+		CLOCKPro().
+		LoaderFunc(loader).
+		PurgeVisitorFunc(func(k, v interface{}) {
+			purgeCount++
+		}).
+		Build()
+
+	for i := 0; i < cacheSize; i++ {
+		_, err := gc.Get(fmt.Sprintf("Key-%d", i))
+		if err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+	}
+
+	gc.Purge()
+
+	if purgeCount != cacheSize {
+		t.Errorf("failed to purge everything")
+	}
+}
+
+func TestClockProGetIFPresent(t *testing.T) {
+	testGetIFPresent(t, TYPE_CLOCK_PRO)
+}
+
+func TestClockProHas(t *testing.T) {
+	gc := buildTestLoadingCacheWithExpiration(t, TYPE_CLOCK_PRO, 2, 10*time.Millisecond)
+
+	for i := 0; i < 10; i++ {
+		t.Run(fmt.Sprint(i), func(t *testing.T) {
+			gc.Get("test1")
+			gc.Get("test2")
+
+			if gc.Has("test0") {
+				t.Fatal("should not have test0")
+			}
+			if !gc.Has("test1") {
+				t.Fatal("should have test1")
+			}
+			if !gc.Has("test2") {
+				t.Fatal("should have test2")
+			}
+
+			time.Sleep(20 * time.Millisecond)
+
+			if gc.Has("test0") {
+				t.Fatal("should not have test0")
+			}
+			if gc.Has("test1") {
+				t.Fatal("should not have test1")
+			}
+			if gc.Has("test2") {
+				t.Fatal("should not have test2")
+			}
+		})
+	}
+}
+
+func TestClockProTestPeriod(t *testing.T) {
+	size := 4
+	gc := buildTestCache(t, TYPE_CLOCK_PRO, size).(*ClockProCache)
+	p := gc.policy.(*clockProPolicy)
+
+	setItemsByRange(t, gc, 0, size+1)
+	if gc.Has(0) {
+		t.Fatal("0 should be evicted")
+	}
+	r, ok := p.items[0]
+	if !ok || r.Value.(*clockProItem).kind != clockProNonResident {
+		t.Fatal("0 should stay as a non-resident page in its test period")
+	}
+	coldSize := p.coldSize
+
+	// a reuse within the test period brings the page back as a hot page
+	gc.Set(0, 0)
+	if kind := p.items[0].Value.(*clockProItem).kind; kind != clockProHot {
+		t.Fatalf("0 should be hot, got %v", kind)
+	}
+	if p.coldSize < coldSize {
+		t.Fatalf("cold size should not shrink on a reuse: %v < %v", p.coldSize, coldSize)
+	}
+	if v, err := gc.Get(0); err != nil || v != 0 {
+		t.Fatalf("0 should be cached: %v, %v", v, err)
+	}
+	if l := gc.Len(false); l != size {
+		t.Fatalf("%v != %v", l, size)
+	}
+}
+
+func TestClockProScanResistance(t *testing.T) {
+	size := 100
+	hot := 20
+	gc := buildTestCache(t, TYPE_CLOCK_PRO, size)
+
+	// make the hot keys hot pages: they are evicted once and reused
+	// within their test period
+	setItemsByRange(t, gc, 0, hot)
+	setItemsByRange(t, gc, 1000, 1000+size)
+	setItemsByRange(t, gc, 0, hot)
+	for i := 0; i < 10*size; i++ {
+		gc.Get(i % hot)
+		setItemsByRange(t, gc, 10000+i, 10001+i)
+	}
+	for i := 0; i < hot; i++ {
+		if !gc.Has(i) {
+			t.Fatalf("hot key %v should survive the scan", i)
+		}
+	}
+}
+
+func TestClockProInvariants(t *testing.T) {
+	size := 32
+	gc := buildTestCache(t, TYPE_CLOCK_PRO, size).(*ClockProCache)
+	p := gc.policy.(*clockProPolicy)
+
+	seed := uint32(1)
+	next := func() int {
+		seed = seed*1664525 + 1013904223
+		return int(seed>>16) % (4 * size)
+	}
+	for i := 0; i < 20000; i++ {
+		key := next()
+		switch i % 7 {
+		case 0:
+			gc.Remove(key)
+		case 1, 2:
+			gc.Get(key)
+		default:
+			gc.Set(key, key)
+		}
+
+		resident := p.countHot + p.countCold
+		if resident > size {
+			t.Fatalf("%v resident pages exceed the size %v", resident, size)
+		}
+		if p.countTest > size {
+			t.Fatalf("%v test pages exceed the size %v", p.countTest, size)
+		}
+		if n := len(p.items); n != resident+p.countTest {
+			t.Fatalf("%v pages indexed, %v counted", n, resident+p.countTest)
+		}
+		if n := len(gc.Keys(false)); n != resident {
+			t.Fatalf("%v keys, %v resident pages", n, resident)
+		}
+		if p.handHot != nil && p.handHot.Len() != len(p.items) {
+			t.Fatalf("%v pages on the clock, %v indexed", p.handHot.Len(), len(p.items))
+		}
+	}
+}
+
+func TestClockProConcurrentGet(t *testing.T) {
+	testConcurrentGet(t, TYPE_CLOCK_PRO)
+}
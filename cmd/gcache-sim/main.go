@@ -0,0 +1,207 @@
+// Command gcache-sim replays a key access trace against the eviction
+// policies of gcache and reports the hit rate and the number of evictions of
+// each policy at several capacities.
+//
+// Usage:
+//
+//	gcache-sim [flags] [trace file]
+//
+// The trace is read from standard input if no file is given. Every request
+// is a Get, followed by a Set if it missed. With -ttl, entries expire after
+// the given time on the clock of the trace, which requires a CSV trace with
+// timestamps. Expired entries count as evictions.
+package main
+
+import (
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/bluele/gcache"
+)
+
+var allTypes = []string{
+	gcache.TYPE_SIMPLE,
+	gcache.TYPE_LRU,
+	gcache.TYPE_LFU,
+	gcache.TYPE_ARC,
+	gcache.TYPE_2Q,
+	gcache.TYPE_SIEVE,
+	gcache.TYPE_S3FIFO,
+	gcache.TYPE_CLOCK,
+	gcache.TYPE_CLOCK_PRO,
+	gcache.TYPE_LIRS,
+	gcache.TYPE_GDSF,
+	gcache.TYPE_ADAPTIVE,
+}
+
+// result is the outcome of replaying a trace against one cache.
+type result struct {
+	tp        string
+	size      int
+	hits      uint64
+	misses    uint64
+	evictions uint64
+}
+
+func (r result) hitRate() float64 {
+	if r.hits+r.misses == 0 {
+		return 0
+	}
+	return float64(r.hits) / float64(r.hits+r.misses)
+}
+
+func main() {
+	var (
+		format    = flag.String("format", formatKeys, "trace format: keys, arc, lirs or csv")
+		types     = flag.String("types", strings.Join(allTypes, ","), "comma separated eviction types to simulate")
+		sizes     = flag.String("sizes", "100,1000,10000", "comma separated cache sizes to simulate")
+		output    = flag.String("output", "table", "output format: table or csv")
+		ttl       = flag.Duration("ttl", 0, "expire entries after this time on the trace clock")
+		keyColumn = flag.Int("csv-key", 1, "column of the key in a CSV trace")
+		timeCol   = flag.Int("csv-time", 0, "column of the timestamp in a CSV trace, or -1 if there is none")
+		header    = flag.Bool("csv-header", false, "skip the first record of a CSV trace")
+	)
+	flag.Parse()
+
+	if err := run(flag.Args(), *format, *types, *sizes, *output, *ttl, csvOptions{
+		keyColumn:  *keyColumn,
+		timeColumn: *timeCol,
+		header:     *header,
+	}); err != nil {
+		fmt.Fprintln(os.Stderr, "gcache-sim:", err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string, format, types, sizes, output string, ttl time.Duration, opts csvOptions) error {
+	var in io.Reader = os.Stdin
+	switch len(args) {
+	case 0:
+	case 1:
+		f, err := os.Open(args[0])
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		in = f
+	default:
+		return fmt.Errorf("expected at most one trace file, got %d", len(args))
+	}
+
+	capacities, err := parseSizes(sizes)
+	if err != nil {
+		return err
+	}
+	if output != "table" && output != "csv" {
+		return fmt.Errorf("unknown output format %q", output)
+	}
+	trace, err := readTrace(in, format, opts)
+	if err != nil {
+		return err
+	}
+	if ttl > 0 && (format != formatCSV || opts.timeColumn < 0) {
+		return fmt.Errorf("-ttl needs a CSV trace with timestamps")
+	}
+
+	var results []result
+	for _, tp := range strings.Split(types, ",") {
+		tp = strings.TrimSpace(tp)
+		for _, size := range capacities {
+			r, err := simulate(trace, tp, size, ttl)
+			if err != nil {
+				return err
+			}
+			results = append(results, r)
+		}
+	}
+	if output == "csv" {
+		return writeCSV(os.Stdout, results)
+	}
+	return writeTable(os.Stdout, results)
+}
+
+func parseSizes(s string) ([]int, error) {
+	var sizes []int
+	for _, f := range strings.Split(s, ",") {
+		size, err := strconv.Atoi(strings.TrimSpace(f))
+		if err != nil || size <= 0 {
+			return nil, fmt.Errorf("invalid cache size %q", f)
+		}
+		sizes = append(sizes, size)
+	}
+	return sizes, nil
+}
+
+// simulate replays trace against a cache of the given type and size.
+func simulate(trace []access, tp string, size int, ttl time.Duration) (r result, err error) {
+	defer func() {
+		// the builder panics on unknown types
+		if v := recover(); v != nil {
+			err = fmt.Errorf("%v", v)
+		}
+	}()
+
+	r = result{tp: tp, size: size}
+	clock := gcache.NewFakeClock()
+	cb := gcache.New(size).
+		EvictType(tp).
+		Clock(clock).
+		EvictedFunc(func(key, value interface{}) {
+			r.evictions++
+		})
+	if ttl > 0 {
+		cb = cb.Expiration(ttl)
+	}
+	gc := cb.Build()
+
+	var now time.Duration
+	for _, a := range trace {
+		if a.time > now {
+			clock.Advance(a.time - now)
+			now = a.time
+		}
+		if _, err := gc.GetIFPresent(a.key); err == nil {
+			continue
+		}
+		if err := gc.Set(a.key, struct{}{}); err != nil {
+			return r, err
+		}
+	}
+	r.hits = gc.HitCount()
+	r.misses = gc.MissCount()
+	return r, nil
+}
+
+func writeTable(w io.Writer, results []result) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "policy\tsize\trequests\thits\thit rate\tevictions\t")
+	for _, r := range results {
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%d\t%.2f%%\t%d\t\n",
+			r.tp, r.size, r.hits+r.misses, r.hits, 100*r.hitRate(), r.evictions)
+	}
+	return tw.Flush()
+}
+
+func writeCSV(w io.Writer, results []result) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"policy", "size", "requests", "hits", "hit_rate", "evictions"})
+	for _, r := range results {
+		cw.Write([]string{
+			r.tp,
+			strconv.Itoa(r.size),
+			strconv.FormatUint(r.hits+r.misses, 10),
+			strconv.FormatUint(r.hits, 10),
+			strconv.FormatFloat(r.hitRate(), 'f', 6, 64),
+			strconv.FormatUint(r.evictions, 10),
+		})
+	}
+	cw.Flush()
+	return cw.Error()
+}
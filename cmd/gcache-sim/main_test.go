@@ -0,0 +1,113 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReadTrace(t *testing.T) {
+	cases := []struct {
+		format string
+		input  string
+		keys   []string
+	}{
+		{formatKeys, "a\n# comment\n\nb\na\n", []string{"a", "b", "a"}},
+		{formatLIRS, "1\n2\n1\n*\n", []string{"1", "2", "1"}},
+		{formatARC, "10 3 0 1\n5 1 0 2\n", []string{"10", "11", "12", "5"}},
+		{formatCSV, "1.5,a\n2,b\n4,a\n", []string{"a", "b", "a"}},
+	}
+	for _, c := range cases {
+		t.Run(c.format, func(t *testing.T) {
+			trace, err := readTrace(strings.NewReader(c.input), c.format, csvOptions{keyColumn: 1, timeColumn: 0})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(trace) != len(c.keys) {
+				t.Fatalf("%v requests, expected %v", len(trace), len(c.keys))
+			}
+			for i, a := range trace {
+				if a.key != c.keys[i] {
+					t.Fatalf("request %v: %q != %q", i, a.key, c.keys[i])
+				}
+			}
+		})
+	}
+}
+
+func TestReadTraceTimestamps(t *testing.T) {
+	input := "time,key\n2024-01-01T00:00:00Z,a\n2024-01-01T00:00:01.5Z,b\n"
+	trace, err := readTrace(strings.NewReader(input), formatCSV, csvOptions{keyColumn: 1, timeColumn: 0, header: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(trace) != 2 || trace[0].time != 0 || trace[1].time != 1500*time.Millisecond {
+		t.Fatalf("unexpected trace %v", trace)
+	}
+}
+
+func TestReadTraceErrors(t *testing.T) {
+	for _, c := range []struct{ format, input string }{
+		{formatLIRS, "x\n"},
+		{formatARC, "1\n"},
+		{formatCSV, "a\n"},
+		{"unknown", ""},
+	} {
+		if _, err := readTrace(strings.NewReader(c.input), c.format, csvOptions{keyColumn: 1, timeColumn: -1}); err == nil {
+			t.Errorf("%v: %q should not parse", c.format, c.input)
+		}
+	}
+}
+
+func TestReadTraceBadColumns(t *testing.T) {
+	for _, opts := range []csvOptions{
+		{keyColumn: -1, timeColumn: -1},
+		{keyColumn: 1, timeColumn: -2},
+	} {
+		if _, err := readTrace(strings.NewReader("0,a\n"), formatCSV, opts); err == nil {
+			t.Errorf("%+v should be rejected", opts)
+		}
+	}
+}
+
+func TestSimulate(t *testing.T) {
+	var trace []access
+	for i := 0; i < 10; i++ {
+		for k := 0; k < 5; k++ {
+			trace = append(trace, access{key: string(rune('a' + k))})
+		}
+	}
+	r, err := simulate(trace, "lru", 5, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.hits != 45 || r.misses != 5 || r.evictions != 0 {
+		t.Fatalf("unexpected result %+v", r)
+	}
+	// a loop larger than an LRU cache never hits
+	r, err = simulate(trace, "lru", 4, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.hits != 0 || r.evictions != 46 {
+		t.Fatalf("unexpected result %+v", r)
+	}
+	if _, err := simulate(trace, "unknown", 4, 0); err == nil {
+		t.Fatal("an unknown type should fail")
+	}
+}
+
+func TestSimulateTTL(t *testing.T) {
+	trace := []access{
+		{key: "a"},
+		{key: "a", time: time.Second},
+		{key: "a", time: 3 * time.Second},
+	}
+	r, err := simulate(trace, "lru", 10, 2*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.hits != 1 || r.misses != 2 {
+		t.Fatalf("unexpected result %+v", r)
+	}
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Trace formats understood by readTrace.
+const (
+	formatKeys = "keys" // one key per line
+	formatARC  = "arc"  // "start count ignored request" per line, as in the ARC paper traces
+	formatLIRS = "lirs" // one block number per line, as in the LIRS paper traces
+	formatCSV  = "csv"  // a timestamp and a key per record
+)
+
+// access is a single request of a trace.
+type access struct {
+	key string
+	// time is the offset from the first request, or 0 if the trace has no
+	// timestamps.
+	time time.Duration
+}
+
+// csvOptions tells readTrace where to find the fields of a CSV trace.
+type csvOptions struct {
+	keyColumn  int
+	timeColumn int // -1 if the trace has no timestamps
+	header     bool
+}
+
+// readTrace parses a trace in the given format.
+func readTrace(r io.Reader, format string, opts csvOptions) ([]access, error) {
+	switch format {
+	case formatKeys:
+		return readLines(r, func(line string, out []access) ([]access, error) {
+			return append(out, access{key: line}), nil
+		})
+	case formatLIRS:
+		return readLines(r, func(line string, out []access) ([]access, error) {
+			// the traces end with a "*" marker
+			if line == "*" {
+				return out, nil
+			}
+			if _, err := strconv.ParseUint(line, 10, 64); err != nil {
+				return nil, fmt.Errorf("invalid block number %q", line)
+			}
+			return append(out, access{key: line}), nil
+		})
+	case formatARC:
+		return readLines(r, func(line string, out []access) ([]access, error) {
+			fields := strings.Fields(line)
+			if len(fields) < 2 {
+				return nil, fmt.Errorf("expected a start block and a block count, got %q", line)
+			}
+			start, err := strconv.ParseUint(fields[0], 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid start block %q", fields[0])
+			}
+			count, err := strconv.ParseUint(fields[1], 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid block count %q", fields[1])
+			}
+			for i := uint64(0); i < count; i++ {
+				out = append(out, access{key: strconv.FormatUint(start+i, 10)})
+			}
+			return out, nil
+		})
+	case formatCSV:
+		return readCSV(r, opts)
+	default:
+		return nil, fmt.Errorf("unknown trace format %q", format)
+	}
+}
+
+// readLines calls parse for every non-empty line of r. Lines starting with
+// '#' are comments.
+func readLines(r io.Reader, parse func(string, []access) ([]access, error)) ([]access, error) {
+	var out []access
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		var err error
+		if out, err = parse(line, out); err != nil {
+			return nil, fmt.Errorf("line %d: %v", n, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func readCSV(r io.Reader, opts csvOptions) ([]access, error) {
+	if opts.keyColumn < 0 {
+		return nil, fmt.Errorf("invalid key column %d", opts.keyColumn)
+	}
+	if opts.timeColumn < -1 {
+		return nil, fmt.Errorf("invalid time column %d", opts.timeColumn)
+	}
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.ReuseRecord = true
+	reader.TrimLeadingSpace = true
+
+	var out []access
+	var first time.Time
+	for n := 1; ; n++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return out, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if n == 1 && opts.header {
+			continue
+		}
+		if opts.keyColumn >= len(record) {
+			return nil, fmt.Errorf("record %d: no key column %d", n, opts.keyColumn)
+		}
+		a := access{key: record[opts.keyColumn]}
+		if opts.timeColumn >= 0 {
+			if opts.timeColumn >= len(record) {
+				return nil, fmt.Errorf("record %d: no time column %d", n, opts.timeColumn)
+			}
+			t, err := parseTime(record[opts.timeColumn])
+			if err != nil {
+				return nil, fmt.Errorf("record %d: %v", n, err)
+			}
+			if len(out) == 0 {
+				first = t
+			}
+			a.time = t.Sub(first)
+		}
+		out = append(out, a)
+	}
+}
+
+// parseTime accepts Unix timestamps in seconds, with an optional fraction,
+// and RFC 3339 times.
+func parseTime(s string) (time.Time, error) {
+	if f, err := strconv.ParseFloat(s, 64); err == nil {
+		sec := int64(f)
+		return time.Unix(sec, int64((f-float64(sec))*float64(time.Second))), nil
+	}
+	t, err := time.Parse(time.RFC3339Nano, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid timestamp %q", s)
+	}
+	return t, nil
+}
@@ -0,0 +1,64 @@
+package gcache
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// Entry describes a cached key-value pair, as returned by GetEntry.
+type Entry struct {
+	Key interface{}
+	// Value is the value as stored, after the SerializeFunc.
+	Value interface{}
+	// Created is the time the value was set or loaded.
+	Created time.Time
+	// LastAccess is the time of the last read, or zero if the value was
+	// not read.
+	LastAccess time.Time
+	// Expiration is the time the entry expires, or zero if it does not.
+	Expiration time.Time
+	// Accesses is the number of reads since the value was set.
+	Accesses uint64
+	// Frequency is the access frequency counted by the LFU policy, which
+	// survives updates and is aged, and 0 for other policies.
+	Frequency uint
+	// Weight is the weight of the entry, 1 without a Weigher.
+	Weight int
+	// List is the list of the policy that holds the key, "t1" or "t2" for
+	// ARC, and empty for other policies.
+	List string
+}
+
+// GetEntry returns the metadata of the entry for key if it is present and
+// not expired. Unlike Get, it does not count as a read of the entry.
+func (c *baseCache) GetEntry(key interface{}) (Entry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	// replay buffered reads for the frequency and list of the policy
+	c.flushReads()
+	e, ok := c.items[key]
+	if !ok {
+		return Entry{}, false
+	}
+	now := c.clock.Now()
+	if e.IsExpired(&now) {
+		return Entry{}, false
+	}
+	ent := Entry{
+		Key:      key,
+		Value:    e.value,
+		Created:  e.created,
+		Accesses: atomic.LoadUint64(&e.reads),
+		Weight:   e.weight,
+	}
+	if t := atomic.LoadInt64(&e.lastRead); t != 0 {
+		ent.LastAccess = time.Unix(0, t)
+	}
+	if t := atomic.LoadInt64(&e.deadline); t != 0 {
+		ent.Expiration = time.Unix(0, t)
+	}
+	if p, ok := c.policy.(inspectablePolicy); ok {
+		p.inspect(key, &ent)
+	}
+	return ent, true
+}
@@ -0,0 +1,69 @@
+package gcache
+
+import (
+	"testing"
+	"time"
+)
+
+func TestGetEntry(t *testing.T) {
+	clock := NewFakeClock()
+	gc := New(10).
+		LRU().
+		Clock(clock).
+		Weigher(func(key, value interface{}) int {
+			return 2
+		}).
+		Build()
+	created := clock.Now()
+	gc.SetWithExpire("key", "value", time.Minute)
+	e, ok := gc.GetEntry("key")
+	if !ok {
+		t.Fatal("the entry is not found")
+	}
+	if e.Key != "key" || e.Value != "value" || e.Weight != 2 || e.Accesses != 0 || !e.LastAccess.IsZero() {
+		t.Errorf("unexpected entry %+v", e)
+	}
+	if !e.Created.Equal(created) || !e.Expiration.Equal(created.Add(time.Minute)) {
+		t.Errorf("unexpected times %v, %v", e.Created, e.Expiration)
+	}
+
+	clock.Advance(time.Second)
+	gc.Get("key")
+	gc.Get("key")
+	e, _ = gc.GetEntry("key")
+	if e.Accesses != 2 || !e.LastAccess.Equal(created.Add(time.Second)) {
+		t.Errorf("unexpected accesses %v at %v", e.Accesses, e.LastAccess)
+	}
+	if n := gc.HitCount(); n != 2 {
+		t.Errorf("%v hits, expected 2", n)
+	}
+
+	if _, ok := gc.GetEntry("missing"); ok {
+		t.Error("a missing entry is found")
+	}
+	clock.Advance(time.Minute)
+	if _, ok := gc.GetEntry("key"); ok {
+		t.Error("an expired entry is found")
+	}
+}
+
+func TestGetEntryPolicy(t *testing.T) {
+	lfu := New(10).LFU().Build()
+	lfu.Set("key", "value")
+	lfu.Get("key")
+	lfu.Get("key")
+	if e, _ := lfu.GetEntry("key"); e.Frequency != 2 {
+		t.Errorf("frequency %v, expected 2", e.Frequency)
+	}
+
+	arc := New(10).ARC().Build()
+	arc.Set("once", "value")
+	arc.Set("twice", "value")
+	arc.Get("twice")
+	if e, _ := arc.GetEntry("once"); e.List != "t1" {
+		t.Errorf("list %q, expected t1", e.List)
+	}
+	if e, _ := arc.GetEntry("twice"); e.List != "t2" {
+		t.Errorf("list %q, expected t2", e.List)
+	}
+}
@@ -0,0 +1,70 @@
+package gcache
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrKeyNotFound is returned when the key is not present in the cache
+	// and cannot be loaded.
+	ErrKeyNotFound = errors.New("Key not found.")
+	// ErrLoaderFailed matches, with errors.Is, every error of the loader
+	// returned by Get, including panics.
+	ErrLoaderFailed = errors.New("gcache: loader failed")
+	// ErrSerialize matches, with errors.Is, every error of the
+	// SerializeFunc and DeserializeFunc.
+	ErrSerialize = errors.New("gcache: serialization failed")
+)
+
+// KeyNotFoundError is returned when the key is not present in the cache.
+//
+// Deprecated: Use ErrKeyNotFound.
+var KeyNotFoundError = ErrKeyNotFound
+
+// LoaderError is the error returned by Get when the loader returns an error.
+// The error of the loader is no longer returned as is: compare it with
+// errors.Is, which unwraps the LoaderError, instead of ==.
+type LoaderError struct {
+	Key interface{}
+	// Err is the last error of the loader, after the retries.
+	Err error
+}
+
+func (e *LoaderError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *LoaderError) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether target is ErrLoaderFailed.
+func (e *LoaderError) Is(target error) bool {
+	return target == ErrLoaderFailed
+}
+
+// SerializeError is the error returned when the SerializeFunc or the
+// DeserializeFunc returns an error.
+type SerializeError struct {
+	Key interface{}
+	// Deserialize tells whether the DeserializeFunc returned Err.
+	Deserialize bool
+	Err         error
+}
+
+func (e *SerializeError) Error() string {
+	if e.Deserialize {
+		return fmt.Sprintf("gcache: deserialize %v: %v", e.Key, e.Err)
+	}
+	return fmt.Sprintf("gcache: serialize %v: %v", e.Key, e.Err)
+}
+
+func (e *SerializeError) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether target is ErrSerialize.
+func (e *SerializeError) Is(target error) bool {
+	return target == ErrSerialize
+}
@@ -0,0 +1,69 @@
+package gcache
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestErrKeyNotFound(t *testing.T) {
+	gc := New(10).LRU().Build()
+	if _, err := gc.Get("key"); !errors.Is(err, ErrKeyNotFound) || !errors.Is(err, KeyNotFoundError) {
+		t.Errorf("unexpected error %v", err)
+	}
+}
+
+func TestLoaderError(t *testing.T) {
+	errLoader := errors.New("loader failed")
+	gc := New(10).
+		LRU().
+		LoaderFunc(func(key interface{}) (interface{}, error) {
+			return nil, errLoader
+		}).
+		Build()
+	_, err := gc.Get("key")
+	if !errors.Is(err, ErrLoaderFailed) || !errors.Is(err, errLoader) {
+		t.Errorf("unexpected error %v", err)
+	}
+	var lerr *LoaderError
+	if !errors.As(err, &lerr) || lerr.Key != "key" || lerr.Err != errLoader {
+		t.Errorf("unexpected error %#v", err)
+	}
+
+	gc = New(10).
+		LRU().
+		LoaderFunc(func(key interface{}) (interface{}, error) {
+			panic("boom")
+		}).
+		Build()
+	if _, err := gc.Get("key"); !errors.Is(err, ErrLoaderFailed) {
+		t.Errorf("unexpected error %v", err)
+	}
+}
+
+func TestSerializeError(t *testing.T) {
+	errSerialize := errors.New("cannot serialize")
+	gc := New(10).
+		LRU().
+		SerializeFunc(func(key, value interface{}) (interface{}, error) {
+			if value == "bad" {
+				return nil, errSerialize
+			}
+			return value, nil
+		}).
+		DeserializeFunc(func(key, value interface{}) (interface{}, error) {
+			return nil, errSerialize
+		}).
+		Build()
+	err := gc.Set("key", "bad")
+	var serr *SerializeError
+	if !errors.Is(err, ErrSerialize) || !errors.As(err, &serr) || serr.Deserialize {
+		t.Errorf("unexpected error %v", err)
+	}
+	if err := gc.Set("key", "good"); err != nil {
+		t.Fatal(err)
+	}
+	_, err = gc.Get("key")
+	if !errors.Is(err, ErrSerialize) || !errors.Is(err, errSerialize) || !errors.As(err, &serr) || !serr.Deserialize {
+		t.Errorf("unexpected error %v", err)
+	}
+}
@@ -0,0 +1,39 @@
+package gcache
+
+import (
+	"math"
+	"time"
+)
+
+// NeverExpire is the lifetime of an entry that does not expire.
+const NeverExpire = time.Duration(math.MaxInt64)
+
+// Expiry decides how long each entry lives, for example from a max-age in
+// the value itself. Every method returns the remaining lifetime of the entry
+// measured from the time of the call, or NeverExpire.
+//
+// An Expiry is consulted by Set and by loaders whose LoaderExpireFunc
+// returns a nil duration. SetWithExpire and SetWithSlidingExpire bypass it,
+// and ExpireAfterRead and ExpireAfterUpdate are only called for entries
+// whose lifetime the Expiry decided.
+// ExpireAfterRead may be called concurrently, without the cache lock.
+type Expiry interface {
+	// ExpireAfterCreate returns the lifetime of a new entry.
+	ExpireAfterCreate(key, value interface{}) time.Duration
+	// ExpireAfterUpdate returns the lifetime of an entry whose value is
+	// replaced. current is the remaining lifetime of the old value.
+	ExpireAfterUpdate(key, value interface{}, current time.Duration) time.Duration
+	// ExpireAfterRead returns the lifetime of an entry after a successful
+	// Get. Returning current leaves it unchanged.
+	ExpireAfterRead(key, value interface{}, current time.Duration) time.Duration
+}
+
+// deadlineAfter returns the time d after now in nanoseconds, or 0 if that is
+// never.
+func deadlineAfter(now time.Time, d time.Duration) int64 {
+	n := now.UnixNano()
+	if d > 0 && int64(d) > math.MaxInt64-n {
+		return 0
+	}
+	return n + int64(d)
+}
@@ -0,0 +1,169 @@
+package gcache
+
+import (
+	"testing"
+	"time"
+)
+
+// maxAge is a value that carries its own lifetime.
+type maxAge struct {
+	age time.Duration
+}
+
+// testExpiry uses the lifetime of maxAge values, keeps it on updates, and
+// renews it on reads if renew is set.
+type testExpiry struct {
+	renew bool
+	reads int
+}
+
+func (x *testExpiry) ExpireAfterCreate(key, value interface{}) time.Duration {
+	if v, ok := value.(maxAge); ok {
+		return v.age
+	}
+	return NeverExpire
+}
+
+func (x *testExpiry) ExpireAfterUpdate(key, value interface{}, current time.Duration) time.Duration {
+	return current
+}
+
+func (x *testExpiry) ExpireAfterRead(key, value interface{}, current time.Duration) time.Duration {
+	x.reads++
+	if x.renew {
+		return x.ExpireAfterCreate(key, value)
+	}
+	return current
+}
+
+func TestExpiry(t *testing.T) {
+	for _, tp := range []string{
+		TYPE_SIMPLE,
+		TYPE_LRU,
+		TYPE_LFU,
+		TYPE_ARC,
+		TYPE_2Q,
+		TYPE_SIEVE,
+		TYPE_S3FIFO,
+		TYPE_CLOCK,
+		TYPE_CLOCK_PRO,
+		TYPE_LIRS,
+		TYPE_GDSF,
+		TYPE_ADAPTIVE,
+	} {
+		t.Run(tp, func(t *testing.T) {
+			clock := NewFakeClock()
+			expiry := &testExpiry{}
+			gc := New(10).
+				EvictType(tp).
+				Clock(clock).
+				Expiry(expiry).
+				Build()
+			gc.Set("short", maxAge{time.Second})
+			gc.Set("long", maxAge{time.Minute})
+			gc.Set("forever", "value")
+
+			clock.Advance(30 * time.Second)
+			if gc.Has("short") {
+				t.Error("short should have expired")
+			}
+			// updates keep the remaining lifetime
+			gc.Set("long", maxAge{time.Hour})
+			if _, err := gc.Get("long"); err != nil {
+				t.Error(err)
+			}
+			clock.Advance(31 * time.Second)
+			if _, err := gc.Get("long"); err != KeyNotFoundError {
+				t.Errorf("long: %v", err)
+			}
+			clock.Advance(time.Hour)
+			if _, err := gc.Get("forever"); err != nil {
+				t.Error(err)
+			}
+			if expiry.reads != 2 {
+				t.Errorf("%v reads, expected 2", expiry.reads)
+			}
+
+			// explicit expirations bypass the Expiry
+			gc.SetWithExpire("short", maxAge{time.Second}, time.Minute)
+			clock.Advance(30 * time.Second)
+			if !gc.Has("short") {
+				t.Error("short should still be cached")
+			}
+		})
+	}
+}
+
+func TestExpiryAfterRead(t *testing.T) {
+	clock := NewFakeClock()
+	gc := New(10).
+		LRU().
+		Clock(clock).
+		Expiry(&testExpiry{renew: true}).
+		Build()
+	gc.Set("key", maxAge{time.Minute})
+	for i := 0; i < 5; i++ {
+		clock.Advance(50 * time.Second)
+		if _, err := gc.Get("key"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	clock.Advance(61 * time.Second)
+	if gc.Has("key") {
+		t.Error("key should have expired")
+	}
+}
+
+func TestExpiryAfterReadWithExplicitExpiration(t *testing.T) {
+	clock := NewFakeClock()
+	expiry := &testExpiry{renew: true}
+	gc := New(10).
+		LRU().
+		Clock(clock).
+		Expiry(expiry).
+		Build()
+	gc.SetWithExpire("key", maxAge{time.Minute}, 5*time.Second)
+	if _, err := gc.Get("key"); err != nil {
+		t.Fatal(err)
+	}
+	if expiry.reads != 0 {
+		t.Errorf("%v reads, expected 0", expiry.reads)
+	}
+	clock.Advance(6 * time.Second)
+	if gc.Has("key") {
+		t.Error("the read should not extend an explicit expiration")
+	}
+
+	// an update without a duration starts a lifetime from the Expiry
+	gc.SetWithExpire("key", maxAge{time.Minute}, 5*time.Second)
+	gc.Set("key", maxAge{time.Minute})
+	clock.Advance(30 * time.Second)
+	if !gc.Has("key") {
+		t.Error("key should still be cached")
+	}
+}
+
+func TestExpiryWithLoader(t *testing.T) {
+	clock := NewFakeClock()
+	ttl := time.Hour
+	gc := New(10).
+		LRU().
+		Clock(clock).
+		Expiry(&testExpiry{}).
+		LoaderExpireFunc(func(key interface{}) (interface{}, *time.Duration, error) {
+			if key == "fixed" {
+				return maxAge{time.Second}, &ttl, nil
+			}
+			return maxAge{time.Second}, nil, nil
+		}).
+		Build()
+	gc.Get("fixed")
+	gc.Get("variable")
+	clock.Advance(time.Minute)
+	if !gc.Has("fixed") {
+		t.Error("the duration of the loader should take precedence")
+	}
+	if gc.Has("variable") {
+		t.Error("the Expiry should decide without a duration from the loader")
+	}
+}
@@ -0,0 +1,64 @@
+package gcache
+
+import (
+	"runtime/debug"
+
+	"github.com/bluele/gcache/singleflight"
+)
+
+// Future is the pending result of GetAsync.
+type Future struct {
+	done  chan struct{}
+	value interface{}
+	err   error
+}
+
+func newFuture() *Future {
+	return &Future{done: make(chan struct{})}
+}
+
+func (f *Future) complete(value interface{}, err error) {
+	f.value, f.err = value, err
+	close(f.done)
+}
+
+// Done returns a channel that is closed once the result is available.
+func (f *Future) Done() <-chan struct{} {
+	return f.done
+}
+
+// Get waits for the result and returns it as Get of the cache would.
+func (f *Future) Get() (interface{}, error) {
+	<-f.done
+	return f.value, f.err
+}
+
+// GetAsync returns the value for key like Get, without waiting for the
+// loader. Loads of the same key are merged with those of Get, and a
+// panicking loader makes the result a *LoaderPanicError, even with
+// RepanicOnLoaderPanic. Other panics, such as of a DeserializeFunc, make it
+// a *singleflight.PanicError with the stack of the panic.
+func (c *baseCache) GetAsync(key interface{}) *Future {
+	f := newFuture()
+	v, err := c.get(key, false)
+	if err != ErrKeyNotFound {
+		f.complete(v, err)
+		return f
+	}
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				switch err := r.(type) {
+				case *LoaderPanicError:
+					f.complete(nil, err)
+				case *singleflight.PanicError:
+					f.complete(nil, err)
+				default:
+					f.complete(nil, &singleflight.PanicError{Value: r, Stack: debug.Stack()})
+				}
+			}
+		}()
+		f.complete(c.getWithLoader(key, true))
+	}()
+	return f
+}
@@ -0,0 +1,101 @@
+package gcache
+
+import (
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/bluele/gcache/singleflight"
+)
+
+func TestGetAsync(t *testing.T) {
+	var loads int32
+	release := make(chan struct{})
+	gc := New(10).
+		LRU().
+		LoaderFunc(func(key interface{}) (interface{}, error) {
+			atomic.AddInt32(&loads, 1)
+			<-release
+			return key, nil
+		}).
+		Build()
+	gc.Set("present", "value")
+
+	f := gc.GetAsync("present")
+	select {
+	case <-f.Done():
+	default:
+		t.Fatal("the future of a present key should be done")
+	}
+	if v, err := f.Get(); err != nil || v != "value" {
+		t.Errorf("%v, %v", v, err)
+	}
+
+	// several loads run at once and are collected together
+	var futures []*Future
+	for i := 0; i < 5; i++ {
+		futures = append(futures, gc.GetAsync(i), gc.GetAsync(i))
+	}
+	close(release)
+	for i, f := range futures {
+		if v, err := f.Get(); err != nil || v != i/2 {
+			t.Errorf("%v: %v, %v", i, v, err)
+		}
+	}
+	if n := atomic.LoadInt32(&loads); n != 5 {
+		t.Errorf("%v loads, expected 5", n)
+	}
+}
+
+func TestGetAsyncPanic(t *testing.T) {
+	gc := New(10).
+		LRU().
+		LoaderFunc(func(key interface{}) (interface{}, error) {
+			panic("boom")
+		}).
+		Build()
+	f := gc.GetAsync("key")
+	select {
+	case <-f.Done():
+	case <-time.After(time.Second):
+		t.Fatal("the future is not done")
+	}
+	if _, err := f.Get(); err == nil || !strings.Contains(err.Error(), "boom") {
+		t.Errorf("unexpected error %v", err)
+	}
+}
+
+func TestGetAsyncDeserializePanic(t *testing.T) {
+	clock := NewFakeClock()
+	gc := New(10).
+		LRU().
+		Clock(clock).
+		LoaderFunc(func(key interface{}) (interface{}, error) {
+			return nil, errors.New("loader failed")
+		}).
+		CircuitBreaker(1, time.Minute).
+		ServeStale().
+		DeserializeFunc(func(key, value interface{}) (interface{}, error) {
+			panic("boom")
+		}).
+		Build()
+	gc.SetWithExpire("key", "value", time.Second)
+	clock.Advance(time.Minute)
+	// the failed load opens the breaker, and serving the stale value panics
+	f := gc.GetAsync("key")
+	select {
+	case <-f.Done():
+	case <-time.After(time.Second):
+		t.Fatal("the future is not done")
+	}
+	_, err := f.Get()
+	perr, ok := err.(*singleflight.PanicError)
+	if !ok || perr.Value != "boom" {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if !strings.Contains(string(perr.Stack), "TestGetAsyncDeserializePanic") {
+		t.Errorf("the stack does not contain the panic:\n%s", perr.Stack)
+	}
+}
@@ -0,0 +1,141 @@
+package gcache
+
+import (
+	"container/heap"
+)
+
+// GDSFCache implements the GreedyDual-Size-Frequency algorithm.
+// Each item has the priority L + freq*cost/weight, where cost tells how
+// expensive the value was to load and weight how much room it takes. The
+// item with the lowest priority is evicted and its priority becomes the new
+// L, so that the priority of items that are no longer accessed ages relative
+// to the ones inserted or accessed later.
+type GDSFCache struct {
+	baseCache
+}
+
+var _ Cache = (*GDSFCache)(nil)
+
+func newGDSFCache(cb *CacheBuilder) *GDSFCache {
+	c := &GDSFCache{}
+	buildCache(&c.baseCache, cb, newGDSFPolicy())
+	return c
+}
+
+type gdsfPolicy struct {
+	items   map[interface{}]*gdsfItem
+	queue   gdsfQueue // lowest priority at the root
+	inflate float64   // L
+}
+
+type gdsfItem struct {
+	key      interface{}
+	freq     float64
+	cost     float64
+	weight   int
+	priority float64
+	index    int
+}
+
+func newGDSFPolicy() *gdsfPolicy {
+	p := &gdsfPolicy{}
+	p.reset()
+	return p
+}
+
+func (p *gdsfPolicy) reset() {
+	p.items = make(map[interface{}]*gdsfItem)
+	p.queue = nil
+	p.inflate = 0
+}
+
+func (p *gdsfPolicy) OnInsert(key interface{}) {
+	item := &gdsfItem{
+		key:    key,
+		freq:   1,
+		cost:   1,
+		weight: 1,
+	}
+	item.priority = p.priority(item)
+	p.items[key] = item
+	heap.Push(&p.queue, item)
+}
+
+func (p *gdsfPolicy) OnAccess(key interface{}) {
+	if item, ok := p.items[key]; ok {
+		item.freq++
+		p.update(item)
+	}
+}
+
+// OnUpdate leaves the frequency of key unchanged: only reads count. The
+// cost and weight of the new value are set by setWeight.
+func (p *gdsfPolicy) OnUpdate(key interface{}) {}
+
+func (p *gdsfPolicy) OnRemove(key interface{}) {
+	if item, ok := p.items[key]; ok {
+		heap.Remove(&p.queue, item.index)
+		delete(p.items, key)
+	}
+}
+
+// Victim returns the key with the lowest priority, and raises L to it.
+func (p *gdsfPolicy) Victim() (interface{}, bool) {
+	if p.queue.Len() == 0 {
+		return nil, false
+	}
+	item := heap.Pop(&p.queue).(*gdsfItem)
+	p.inflate = item.priority
+	delete(p.items, item.key)
+	return item.key, true
+}
+
+// setWeight records the weight of key. A cost of 0 keeps the cost of
+// the item.
+func (p *gdsfPolicy) setWeight(key interface{}, weight int, cost float64) {
+	if item, ok := p.items[key]; ok {
+		item.weight = weight
+		if cost > 0 {
+			item.cost = cost
+		}
+		p.update(item)
+	}
+}
+
+func (p *gdsfPolicy) update(item *gdsfItem) {
+	item.priority = p.priority(item)
+	heap.Fix(&p.queue, item.index)
+}
+
+func (p *gdsfPolicy) priority(item *gdsfItem) float64 {
+	return p.inflate + item.freq*item.cost/float64(item.weight)
+}
+
+// gdsfQueue implements heap.Interface ordered by priority.
+type gdsfQueue []*gdsfItem
+
+func (q gdsfQueue) Len() int { return len(q) }
+
+func (q gdsfQueue) Less(i, j int) bool { return q[i].priority < q[j].priority }
+
+func (q gdsfQueue) Swap(i, j int) {
+	q[i], q[j] = q[j], q[i]
+	q[i].index = i
+	q[j].index = j
+}
+
+func (q *gdsfQueue) Push(x interface{}) {
+	item := x.(*gdsfItem)
+	item.index = len(*q)
+	*q = append(*q, item)
+}
+
+func (q *gdsfQueue) Pop() interface{} {
+	old := *q
+	n := len(old)
+	item := old[n-1]
+	old[n-1] = nil
+	item.index = -1
+	*q = old[:n-1]
+	return item
+}
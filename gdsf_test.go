@@ -0,0 +1,312 @@
+package gcache
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGDSFGet(t *testing.T) {
+	size := 1000
+	gc := buildTestCache(t, TYPE_GDSF, size)
+	testSetCache(t, gc, size)
+	testGetCache(t, gc, size)
+}
+
+func TestLoadingGDSFGet(t *testing.T) {
+	size := 1000
+	numbers := 1000
+	testGetCache(t, buildTestLoadingCache(t, TYPE_GDSF, size, loader), numbers)
+}
+
+func TestGDSFLength(t *testing.T) {
+	clock := NewFakeClock()
+	gc := buildTestLoadingCacheWithClock(t, TYPE_GDSF, 2, time.Millisecond, clock)
+	gc.Get("test1")
+	gc.Get("test2")
+	gc.Get("test3")
+	length := gc.Len(true)
+	expectedLength := 2
+	if length != expectedLength {
+		t.Errorf("Expected length is %v, not %v", expectedLength, length)
+	}
+	clock.Advance(2 * time.Millisecond)
+	gc.Get("test4")
+	length = gc.Len(true)
+	expectedLength = 1
+	if length != expectedLength {
+		t.Errorf("Expected length is %v, not %v", expectedLength, length)
+	}
+}
+
+func TestGDSFEvictItem(t *testing.T) {
+	cacheSize := 10
+	numbers := cacheSize + 1
+	gc := buildTestLoadingCache(t, TYPE_GDSF, cacheSize, loader)
+
+	for i := 0; i < numbers; i++ {
+		_, err := gc.Get(fmt.Sprintf("Key-%d", i))
+		if err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+	}
+	if l := gc.Len(false); l != cacheSize {
+		t.Errorf("Expected length is %v, not %v", cacheSize, l)
+	}
+}
+
+func TestGDSFPurgeCache(t *testing.T) {
+	cacheSize := 10
+	purgeCount := 0
+	gc := New(cacheSize).
+		GDSF().
+		LoaderFunc(loader).
+		PurgeVisitorFunc(func(k, v interface{}) {
+			purgeCount++
+		}).
+		Build()
+
+	for i := 0; i < cacheSize; i++ {
+		_, err := gc.Get(fmt.Sprintf("Key-%d", i))
+		if err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+	}
+
+	gc.Purge()
+
+	if purgeCount != cacheSize {
+		t.Errorf("failed to purge everything")
+	}
+}
+
+func TestGDSFGetIFPresent(t *testing.T) {
+	testGetIFPresent(t, TYPE_GDSF)
+}
+
+func TestGDSFHas(t *testing.T) {
+	gc := buildTestLoadingCacheWithExpiration(t, TYPE_GDSF, 2, 10*time.Millisecond)
+
+	for i := 0; i < 10; i++ {
+		t.Run(fmt.Sprint(i), func(t *testing.T) {
+			gc.Get("test1")
+			gc.Get("test2")
+
+			if gc.Has("test0") {
+				t.Fatal("should not have test0")
+			}
+			if !gc.Has("test1") {
+				t.Fatal("should have test1")
+			}
+			if !gc.Has("test2") {
+				t.Fatal("should have test2")
+			}
+
+			time.Sleep(20 * time.Millisecond)
+
+			if gc.Has("test0") {
+				t.Fatal("should not have test0")
+			}
+			if gc.Has("test1") {
+				t.Fatal("should not have test1")
+			}
+			if gc.Has("test2") {
+				t.Fatal("should not have test2")
+			}
+		})
+	}
+}
+
+func TestGDSFFrequency(t *testing.T) {
+	size := 4
+	gc := buildTestCache(t, TYPE_GDSF, size)
+
+	setItemsByRange(t, gc, 0, size)
+	for i := 1; i < size; i++ {
+		gc.Get(i)
+	}
+	setItemsByRange(t, gc, size, size+1)
+	if gc.Has(0) {
+		t.Fatal("0 has the lowest frequency and should be evicted")
+	}
+	for i := 1; i <= size; i++ {
+		if !gc.Has(i) {
+			t.Fatalf("%v should be kept", i)
+		}
+	}
+}
+
+func TestGDSFUpdate(t *testing.T) {
+	size := 4
+	gc := buildTestCache(t, TYPE_GDSF, size).(*GDSFCache)
+	p := gc.policy.(*gdsfPolicy)
+
+	setItemsByRange(t, gc, 0, size)
+	gc.Get(1)
+	for i := 0; i < 10; i++ {
+		if err := gc.Set(0, i); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if item := p.items[0]; item.freq != 1 || item.priority != 1 {
+		t.Fatalf("overwrites changed the frequency %v and priority %v", item.freq, item.priority)
+	}
+	if item := p.items[1]; item.freq != 2 || item.priority != 2 {
+		t.Fatalf("a read should raise the frequency %v and priority %v", item.freq, item.priority)
+	}
+}
+
+func TestGDSFCost(t *testing.T) {
+	size := 10
+	gc := New(size).
+		GDSF().
+		LoaderFunc(func(key interface{}) (interface{}, error) {
+			return key, nil
+		}).
+		Cost(func(key, value interface{}, elapsed time.Duration) float64 {
+			if key.(int) < 5 {
+				return 100
+			}
+			return 1
+		}).
+		Build()
+
+	for i := 0; i < 100; i++ {
+		if _, err := gc.Get(i); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i := 0; i < 5; i++ {
+		if !gc.Has(i) {
+			t.Fatalf("expensive key %v should be kept", i)
+		}
+	}
+}
+
+func TestGDSFLoadTimeCost(t *testing.T) {
+	size := 10
+	clock := NewFakeClock()
+	gc := New(size).
+		GDSF().
+		Clock(clock).
+		LoaderFunc(func(key interface{}) (interface{}, error) {
+			if key.(int) == 0 {
+				clock.Advance(time.Second)
+			}
+			return key, nil
+		}).
+		Build()
+
+	for i := 0; i < 100; i++ {
+		if _, err := gc.Get(i); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if !gc.Has(0) {
+		t.Fatal("the slow loaded key should be kept")
+	}
+}
+
+func TestGDSFWeight(t *testing.T) {
+	size := 100
+	var evicted []interface{}
+	gc := New(size).
+		GDSF().
+		Weigher(func(key, value interface{}) int {
+			return len(value.(string))
+		}).
+		EvictedFunc(func(key, value interface{}) {
+			evicted = append(evicted, key)
+		}).
+		Build()
+
+	gc.Set("big", strings.Repeat("x", 50))
+	for i := 0; i < 5; i++ {
+		gc.Set(i, strings.Repeat("x", 10))
+	}
+	// making room for 10 more units evicts the big item, which has the
+	// lowest priority per unit of weight
+	gc.Set(5, strings.Repeat("x", 10))
+	if len(evicted) != 1 || evicted[0] != "big" {
+		t.Fatalf("only big should be evicted, got %v", evicted)
+	}
+	if w := gc.(*GDSFCache).weight; w != 60 {
+		t.Fatalf("total weight should be 60, but got %v", w)
+	}
+
+	// a value heavier than the cache still replaces everything else
+	gc.Set("huge", strings.Repeat("x", 200))
+	if l := gc.Len(false); l != 1 {
+		t.Fatalf("%v != %v", l, 1)
+	}
+}
+
+func TestGDSFAging(t *testing.T) {
+	size := 10
+	gc := buildTestCache(t, TYPE_GDSF, size)
+
+	// 0 was popular once
+	gc.Set(0, 0)
+	for i := 0; i < 5; i++ {
+		gc.Get(0)
+	}
+	// every eviction raises L, so newer items eventually outrank it
+	for i := 1; i < 20*size; i++ {
+		gc.Set(i, i)
+		gc.Get(i)
+	}
+	if gc.Has(0) {
+		t.Fatal("0 should age out of the cache")
+	}
+}
+
+func TestGDSFInvariants(t *testing.T) {
+	size := 64
+	gc := New(size).
+		GDSF().
+		Weigher(func(key, value interface{}) int {
+			return value.(int)%8 + 1
+		}).
+		Build().(*GDSFCache)
+	p := gc.policy.(*gdsfPolicy)
+
+	seed := uint32(1)
+	next := func() int {
+		seed = seed*1664525 + 1013904223
+		return int(seed>>16) % (4 * size)
+	}
+	for i := 0; i < 20000; i++ {
+		key := next()
+		switch i % 7 {
+		case 0:
+			gc.Remove(key)
+		case 1, 2:
+			gc.Get(key)
+		default:
+			gc.Set(key, key+i)
+		}
+
+		var weight int
+		for key, e := range gc.items {
+			weight += e.weight
+			item, ok := p.items[key]
+			if !ok || p.queue[item.index] != item {
+				t.Fatalf("%v is not at its index in the queue", key)
+			}
+			if item.weight != e.weight {
+				t.Fatalf("%v weighs %v in the queue, %v in the cache", key, item.weight, e.weight)
+			}
+		}
+		if weight != gc.weight || weight > size {
+			t.Fatalf("total weight %v, counted %v, size %v", weight, gc.weight, size)
+		}
+		if n := p.queue.Len(); n != len(gc.items) {
+			t.Fatalf("%v queued, %v indexed", n, len(gc.items))
+		}
+	}
+}
+
+func TestGDSFConcurrentGet(t *testing.T) {
+	testConcurrentGet(t, TYPE_GDSF)
+}
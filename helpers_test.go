@@ -2,6 +2,7 @@ package gcache
 
 import (
 	"fmt"
+	"sync"
 	"testing"
 	"time"
 )
@@ -161,3 +162,41 @@ func buildTestLoadingCacheWithExpiration(t *testing.T, tp string, size int, ep t
 		EvictedFunc(getSimpleEvictedFunc(t)).
 		Build()
 }
+
+func buildTestLoadingCacheWithClock(t *testing.T, tp string, size int, ep time.Duration, clock Clock) Cache {
+	return New(size).
+		EvictType(tp).
+		Clock(clock).
+		Expiration(ep).
+		LoaderFunc(loader).
+		EvictedFunc(getSimpleEvictedFunc(t)).
+		Build()
+}
+
+func testConcurrentGet(t *testing.T, evT string) {
+	size := 100
+	gc := New(size).
+		EvictType(evT).
+		Expiration(time.Hour).
+		Build()
+	setItemsByRange(t, gc, 0, size)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 1000; j++ {
+				key := (i + j) % (2 * size)
+				v, err := gc.Get(key)
+				if key < size && (err != nil || v != key) {
+					t.Errorf("%v should be cached: %v, %v", key, v, err)
+				}
+				if j%10 == 0 {
+					gc.Set(key%size, key%size)
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+}
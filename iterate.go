@@ -0,0 +1,52 @@
+package gcache
+
+// Ascend calls fn for the key-value pairs in the cache, from the coldest,
+// which the policy would evict next, to the hottest, until fn returns false.
+// The order is that of the LRU list, of the LFU frequencies, and of the t1
+// and t2 lists of ARC; it is unspecified for the other policies.
+//
+// The lock is only held to copy the keys, so fn may use the cache. Pairs
+// that are removed or expire during the iteration are skipped, and pairs
+// added are not visited. Values are passed as stored, like GetALL.
+// Ascend is an iter.Seq2, so with Go 1.23 it can be used in a range loop.
+func (c *baseCache) Ascend(fn func(key, value interface{}) bool) {
+	c.iterate(c.orderedKeys(), fn)
+}
+
+// Descend calls fn like Ascend, from the hottest pair to the coldest.
+func (c *baseCache) Descend(fn func(key, value interface{}) bool) {
+	keys := c.orderedKeys()
+	for i, j := 0, len(keys)-1; i < j; i, j = i+1, j-1 {
+		keys[i], keys[j] = keys[j], keys[i]
+	}
+	c.iterate(keys, fn)
+}
+
+// orderedKeys returns the keys in the cache from the coldest to the hottest.
+func (c *baseCache) orderedKeys() []interface{} {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	// replay buffered reads into the order of the policy
+	c.flushReads()
+	if p, ok := c.policy.(orderedPolicy); ok {
+		return p.order()
+	}
+	keys := make([]interface{}, 0, len(c.items))
+	for k := range c.items {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+func (c *baseCache) iterate(keys []interface{}, fn func(key, value interface{}) bool) {
+	now := c.clock.Now()
+	for _, k := range keys {
+		e, ok := c.lookup(k)
+		if !ok || e.IsExpired(&now) {
+			continue
+		}
+		if !fn(k, e.value) {
+			return
+		}
+	}
+}
@@ -0,0 +1,31 @@
+//go:build go1.23
+// +build go1.23
+
+package gcache
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRangeOverAscend(t *testing.T) {
+	gc := New(10).LRU().Build()
+	setItemsByRange(t, gc, 0, 3)
+	var keys []interface{}
+	for k := range gc.Ascend {
+		keys = append(keys, k)
+	}
+	if !reflect.DeepEqual(keys, []interface{}{0, 1, 2}) {
+		t.Errorf("keys %v", keys)
+	}
+	keys = nil
+	for k := range gc.Descend {
+		keys = append(keys, k)
+		if len(keys) == 2 {
+			break
+		}
+	}
+	if !reflect.DeepEqual(keys, []interface{}{2, 1}) {
+		t.Errorf("keys %v", keys)
+	}
+}
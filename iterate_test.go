@@ -0,0 +1,83 @@
+package gcache
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func ascendKeys(c Cache) []interface{} {
+	var keys []interface{}
+	c.Ascend(func(key, value interface{}) bool {
+		keys = append(keys, key)
+		return true
+	})
+	return keys
+}
+
+func TestAscendLRU(t *testing.T) {
+	gc := New(10).LRU().Build()
+	setItemsByRange(t, gc, 0, 4)
+	gc.Get(1)
+	if keys := ascendKeys(gc); !reflect.DeepEqual(keys, []interface{}{0, 2, 3, 1}) {
+		t.Errorf("ascending keys %v", keys)
+	}
+
+	var keys []interface{}
+	gc.Descend(func(key, value interface{}) bool {
+		if key != value {
+			t.Errorf("value %v for key %v", value, key)
+		}
+		keys = append(keys, key)
+		return len(keys) < 2
+	})
+	if !reflect.DeepEqual(keys, []interface{}{1, 3}) {
+		t.Errorf("descending keys %v", keys)
+	}
+}
+
+func TestAscendLFU(t *testing.T) {
+	gc := New(10).LFU().Build()
+	setItemsByRange(t, gc, 0, 3)
+	gc.Get(0)
+	gc.Get(0)
+	gc.Get(2)
+	keys := ascendKeys(gc)
+	if len(keys) != 3 || keys[0] != 1 || keys[1] != 2 || keys[2] != 0 {
+		t.Errorf("ascending keys %v", keys)
+	}
+}
+
+func TestAscendARC(t *testing.T) {
+	gc := New(10).ARC().Build()
+	setItemsByRange(t, gc, 0, 3)
+	gc.Get(0)
+	if keys := ascendKeys(gc); !reflect.DeepEqual(keys, []interface{}{1, 2, 0}) {
+		t.Errorf("ascending keys %v", keys)
+	}
+}
+
+func TestAscendSkipsExpired(t *testing.T) {
+	clock := NewFakeClock()
+	gc := New(10).Simple().Clock(clock).Build()
+	gc.SetWithExpire("expired", 0, time.Second)
+	gc.Set("key", 1)
+	clock.Advance(time.Minute)
+	if keys := ascendKeys(gc); !reflect.DeepEqual(keys, []interface{}{"key"}) {
+		t.Errorf("ascending keys %v", keys)
+	}
+}
+
+func TestAscendUsesCache(t *testing.T) {
+	gc := New(10).LRU().Build()
+	setItemsByRange(t, gc, 0, 4)
+	var n int
+	gc.Ascend(func(key, value interface{}) bool {
+		gc.Remove(3)
+		n++
+		return true
+	})
+	if n != 3 {
+		t.Errorf("%v pairs visited, expected 3", n)
+	}
+}
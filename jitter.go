@@ -0,0 +1,29 @@
+package gcache
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// JitterFunc returns the expiration to use for key in place of expiration.
+// It may be called concurrently.
+type JitterFunc func(key interface{}, expiration time.Duration) time.Duration
+
+// RandomJitter returns a JitterFunc that shortens every expiration by a
+// random share of up to fraction, which is in [0, 1]. The shares are drawn
+// from a source seeded with seed, so a single goroutine writing the same keys
+// gets the same expirations every time.
+func RandomJitter(fraction float64, seed int64) JitterFunc {
+	if fraction < 0 || fraction > 1 {
+		panic("gcache: jitter fraction must be in [0, 1]")
+	}
+	var mu sync.Mutex
+	rnd := rand.New(rand.NewSource(seed))
+	return func(key interface{}, expiration time.Duration) time.Duration {
+		mu.Lock()
+		share := rnd.Float64() * fraction
+		mu.Unlock()
+		return expiration - time.Duration(share*float64(expiration))
+	}
+}
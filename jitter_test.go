@@ -0,0 +1,80 @@
+package gcache
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRandomJitter(t *testing.T) {
+	a, b := RandomJitter(0.2, 1), RandomJitter(0.2, 1)
+	distinct := make(map[time.Duration]bool)
+	for i := 0; i < 1000; i++ {
+		x, y := a(i, time.Minute), b(i, time.Minute)
+		if x != y {
+			t.Fatalf("%v != %v with the same seed", x, y)
+		}
+		if x < 48*time.Second || x > time.Minute {
+			t.Fatalf("%v out of range", x)
+		}
+		distinct[x] = true
+	}
+	if len(distinct) < 900 {
+		t.Errorf("only %v distinct expirations", len(distinct))
+	}
+	if d := RandomJitter(0, 1)("key", time.Minute); d != time.Minute {
+		t.Errorf("%v != %v", d, time.Minute)
+	}
+}
+
+func TestExpirationJitter(t *testing.T) {
+	clock := NewFakeClock()
+	n := 1000
+	gc := New(n).
+		LRU().
+		Clock(clock).
+		Expiration(10 * time.Minute).
+		ExpirationJitterFunc(RandomJitter(0.1, 42)).
+		Build()
+	for i := 0; i < n; i++ {
+		gc.Set(i, i)
+	}
+
+	clock.Advance(9 * time.Minute)
+	if l := gc.Len(true); l != n {
+		t.Fatalf("%v entries expired before the window", n-l)
+	}
+	// the entries expire over the whole window
+	for i := 0; i < 6; i++ {
+		clock.Advance(10 * time.Second)
+		l := gc.Len(true)
+		if expected := n - n*(i+1)/6; l < expected-100 || l > expected+100 {
+			t.Errorf("%v entries after %v, expected about %v", l, 9*time.Minute+time.Duration(i+1)*10*time.Second, expected)
+		}
+	}
+	if l := gc.Len(true); l != 0 {
+		t.Errorf("%v entries outlived the expiration", l)
+	}
+}
+
+func TestExpirationJitterFunc(t *testing.T) {
+	clock := NewFakeClock()
+	halve := func(key interface{}, expiration time.Duration) time.Duration {
+		return expiration / 2
+	}
+	gc := New(10).
+		LRU().
+		Clock(clock).
+		ExpirationJitterFunc(halve).
+		ExpireAfterAccess(time.Hour).
+		Build()
+	gc.SetWithExpire("fixed", 1, time.Minute)
+	gc.Set("sliding", 2)
+	clock.Advance(31 * time.Second)
+	if gc.Has("fixed") {
+		t.Error("the expiration of fixed should have been halved")
+	}
+	clock.Advance(45 * time.Minute)
+	if !gc.Has("sliding") {
+		t.Error("sliding expirations should not be changed")
+	}
+}
@@ -6,20 +6,37 @@ import (
 )
 
 // Discards the least frequently used items first.
+// Frequencies can be capped, and halved periodically so that items that
+// are no longer used lose the counts they gathered in the past.
 type LFUCache struct {
 	baseCache
-	items    map[interface{}]*lfuItem
-	freqList *list.List // list for freqEntry
 }
 
 var _ Cache = (*LFUCache)(nil)
 
+func newLFUCache(cb *CacheBuilder) *LFUCache {
+	c := &LFUCache{}
+	buildCache(&c.baseCache, cb, newLFUPolicy(cb))
+	return c
+}
+
+// lfuPolicy groups the keys by access count in a list of increasing
+// frequencies and evicts a key from the lowest one.
+type lfuPolicy struct {
+	clock    Clock
+	items    map[interface{}]*lfuItem
+	freqList *list.List // list for freqEntry
+
+	maxFreq       uint
+	agingInterval time.Duration
+	agingSamples  int
+	lastAging     time.Time
+	samples       int // accesses since the last aging
+}
+
 type lfuItem struct {
-	clock       Clock
 	key         interface{}
-	value       interface{}
 	freqElement *list.Element
-	expiration  *time.Time
 }
 
 type freqEntry struct {
@@ -27,174 +44,107 @@ type freqEntry struct {
 	items map[*lfuItem]struct{}
 }
 
-func newLFUCache(cb *CacheBuilder) *LFUCache {
-	c := &LFUCache{}
-	buildCache(&c.baseCache, cb)
-
-	c.init()
-	c.loadGroup.cache = c
-	return c
+func newLFUPolicy(cb *CacheBuilder) *lfuPolicy {
+	p := &lfuPolicy{
+		clock:         cb.clock,
+		maxFreq:       cb.lfuMaxFreq,
+		agingInterval: cb.lfuAgingInterval,
+		agingSamples:  cb.lfuAgingSamples,
+	}
+	p.reset()
+	return p
 }
 
-func (c *LFUCache) init() {
-	c.freqList = list.New()
-	c.items = make(map[interface{}]*lfuItem, c.size)
-	c.freqList.PushFront(&freqEntry{
+func (p *lfuPolicy) reset() {
+	p.lastAging = p.clock.Now()
+	p.samples = 0
+	p.freqList = list.New()
+	p.items = make(map[interface{}]*lfuItem)
+	p.freqList.PushFront(&freqEntry{
 		freq:  0,
 		items: make(map[*lfuItem]struct{}),
 	})
 }
 
-// Set a new key-value pair
-func (c *LFUCache) Set(key, value interface{}) error {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-	_, err := c.set(key, value)
-	return err
-}
-
-// Set a new key-value pair with an expiration time
-func (c *LFUCache) SetWithExpire(key, value interface{}, expiration time.Duration) error {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-	item, err := c.set(key, value)
-	if err != nil {
-		return err
+func (p *lfuPolicy) OnInsert(key interface{}) {
+	if p.agingInterval > 0 {
+		p.ageAfterInterval()
 	}
-
-	t := c.clock.Now().Add(expiration)
-	item.(*lfuItem).expiration = &t
-	return nil
-}
-
-func (c *LFUCache) set(key, value interface{}) (interface{}, error) {
-	var err error
-	if c.serializeFunc != nil {
-		value, err = c.serializeFunc(key, value)
-		if err != nil {
-			return nil, err
-		}
-	}
-
-	// Check for existing item
-	item, ok := c.items[key]
-	if ok {
-		item.value = value
-	} else {
-		// Verify size not exceeded
-		if len(c.items) >= c.size {
-			c.evict(1)
-		}
-		item = &lfuItem{
-			clock:       c.clock,
-			key:         key,
-			value:       value,
-			freqElement: nil,
-		}
-		el := c.freqList.Front()
-		fe := el.Value.(*freqEntry)
-		fe.items[item] = struct{}{}
-
-		item.freqElement = el
-		c.items[key] = item
+	el := p.freqList.Front()
+	item := &lfuItem{
+		key:         key,
+		freqElement: el,
 	}
+	el.Value.(*freqEntry).items[item] = struct{}{}
+	p.items[key] = item
+}
 
-	if c.expiration != nil {
-		t := c.clock.Now().Add(*c.expiration)
-		item.expiration = &t
+func (p *lfuPolicy) OnAccess(key interface{}) {
+	if item, ok := p.items[key]; ok {
+		p.increment(item)
 	}
+}
 
-	if c.addedFunc != nil {
-		c.addedFunc(key, value)
+// OnUpdate leaves the frequency of key unchanged: only reads count. Like
+// every write, it ages the frequencies once the interval has passed.
+func (p *lfuPolicy) OnUpdate(key interface{}) {
+	if p.agingInterval > 0 {
+		p.ageAfterInterval()
 	}
-
-	return item, nil
 }
 
-// Get a value from cache pool using key if it exists.
-// If it does not exists key and has LoaderFunc,
-// generate a value using `LoaderFunc` method returns value.
-func (c *LFUCache) Get(key interface{}) (interface{}, error) {
-	v, err := c.get(key, false)
-	if err == KeyNotFoundError {
-		return c.getWithLoader(key, true)
+func (p *lfuPolicy) OnRemove(key interface{}) {
+	if item, ok := p.items[key]; ok {
+		p.removeItem(item)
 	}
-	return v, err
 }
 
-// GetIFPresent gets a value from cache pool using key if it exists.
-// If it does not exists key, returns KeyNotFoundError.
-// And send a request which refresh value for specified key if cache object has LoaderFunc.
-func (c *LFUCache) GetIFPresent(key interface{}) (interface{}, error) {
-	v, err := c.get(key, false)
-	if err == KeyNotFoundError {
-		return c.getWithLoader(key, false)
+func (p *lfuPolicy) inspect(key interface{}, e *Entry) {
+	if item, ok := p.items[key]; ok {
+		e.Frequency = item.freqElement.Value.(*freqEntry).freq
 	}
-	return v, err
 }
 
-func (c *LFUCache) get(key interface{}, onLoad bool) (interface{}, error) {
-	v, err := c.getValue(key, onLoad)
-	if err != nil {
-		return nil, err
-	}
-	if c.deserializeFunc != nil {
-		return c.deserializeFunc(key, v)
+// order lists the keys by increasing frequency, in no particular order
+// within a frequency.
+func (p *lfuPolicy) order() []interface{} {
+	keys := make([]interface{}, 0, len(p.items))
+	for e := p.freqList.Front(); e != nil; e = e.Next() {
+		for item := range e.Value.(*freqEntry).items {
+			keys = append(keys, item.key)
+		}
 	}
-	return v, nil
+	return keys
 }
 
-func (c *LFUCache) getValue(key interface{}, onLoad bool) (interface{}, error) {
-	c.mu.Lock()
-	item, ok := c.items[key]
-	if ok {
-		if !item.IsExpired(nil) {
-			c.increment(item)
-			v := item.value
-			c.mu.Unlock()
-			if !onLoad {
-				c.stats.IncrHitCount()
-			}
-			return v, nil
+// Victim returns a key with the lowest frequency.
+func (p *lfuPolicy) Victim() (interface{}, bool) {
+	for e := p.freqList.Front(); e != nil; e = e.Next() {
+		for item := range e.Value.(*freqEntry).items {
+			p.removeItem(item)
+			return item.key, true
 		}
-		c.removeItem(item)
-	}
-	c.mu.Unlock()
-	if !onLoad {
-		c.stats.IncrMissCount()
 	}
-	return nil, KeyNotFoundError
+	return nil, false
 }
 
-func (c *LFUCache) getWithLoader(key interface{}, isWait bool) (interface{}, error) {
-	if c.loaderExpireFunc == nil {
-		return nil, KeyNotFoundError
-	}
-	value, _, err := c.load(key, func(v interface{}, expiration *time.Duration, e error) (interface{}, error) {
-		if e != nil {
-			return nil, e
-		}
-		c.mu.Lock()
-		defer c.mu.Unlock()
-		item, err := c.set(key, v)
-		if err != nil {
-			return nil, err
-		}
-		if expiration != nil {
-			t := c.clock.Now().Add(*expiration)
-			item.(*lfuItem).expiration = &t
-		}
-		return v, nil
-	}, isWait)
-	if err != nil {
-		return nil, err
+func (p *lfuPolicy) removeItem(item *lfuItem) {
+	entry := item.freqElement.Value.(*freqEntry)
+	delete(p.items, item.key)
+	delete(entry.items, item)
+	if isRemovableFreqEntry(entry) {
+		p.freqList.Remove(item.freqElement)
 	}
-	return value, nil
 }
 
-func (c *LFUCache) increment(item *lfuItem) {
+func (p *lfuPolicy) increment(item *lfuItem) {
+	defer p.afterIncrement()
+
 	currentFreqElement := item.freqElement
 	currentFreqEntry := currentFreqElement.Value.(*freqEntry)
+	if p.maxFreq > 0 && currentFreqEntry.freq >= p.maxFreq {
+		return
+	}
 	nextFreq := currentFreqEntry.freq + 1
 	delete(currentFreqEntry.items, item)
 
@@ -209,14 +159,14 @@ func (c *LFUCache) increment(item *lfuItem) {
 			currentFreqEntry.freq = nextFreq
 			nextFreqElement = currentFreqElement
 		} else {
-			nextFreqElement = c.freqList.InsertAfter(&freqEntry{
+			nextFreqElement = p.freqList.InsertAfter(&freqEntry{
 				freq:  nextFreq,
 				items: make(map[*lfuItem]struct{}),
 			}, currentFreqElement)
 		}
 	case nextFreqElement.Value.(*freqEntry).freq == nextFreq:
 		if removable {
-			c.freqList.Remove(currentFreqElement)
+			p.freqList.Remove(currentFreqElement)
 		}
 	default:
 		panic("unreachable")
@@ -225,151 +175,49 @@ func (c *LFUCache) increment(item *lfuItem) {
 	item.freqElement = nextFreqElement
 }
 
-// evict removes the least frequence item from the cache.
-func (c *LFUCache) evict(count int) {
-	entry := c.freqList.Front()
-	for i := 0; i < count; {
-		if entry == nil {
+// afterIncrement ages the frequencies once enough accesses have been
+// counted or enough time has passed.
+func (p *lfuPolicy) afterIncrement() {
+	if p.agingSamples > 0 {
+		p.samples++
+		if p.samples >= p.agingSamples {
+			p.age()
 			return
-		} else {
-			for item := range entry.Value.(*freqEntry).items {
-				if i >= count {
-					return
-				}
-				c.removeItem(item)
-				i++
-			}
-			entry = entry.Next()
 		}
 	}
-}
-
-// Has checks if key exists in cache
-func (c *LFUCache) Has(key interface{}) bool {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
-	now := time.Now()
-	return c.has(key, &now)
-}
-
-func (c *LFUCache) has(key interface{}, now *time.Time) bool {
-	item, ok := c.items[key]
-	if !ok {
-		return false
-	}
-	return !item.IsExpired(now)
-}
-
-// Remove removes the provided key from the cache.
-func (c *LFUCache) Remove(key interface{}) bool {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-
-	return c.remove(key)
-}
-
-func (c *LFUCache) remove(key interface{}) bool {
-	if item, ok := c.items[key]; ok {
-		c.removeItem(item)
-		return true
+	if p.agingInterval > 0 {
+		p.ageAfterInterval()
 	}
-	return false
 }
 
-// removeElement is used to remove a given list element from the cache
-func (c *LFUCache) removeItem(item *lfuItem) {
-	entry := item.freqElement.Value.(*freqEntry)
-	delete(c.items, item.key)
-	delete(entry.items, item)
-	if isRemovableFreqEntry(entry) {
-		c.freqList.Remove(item.freqElement)
-	}
-	if c.evictedFunc != nil {
-		c.evictedFunc(item.key, item.value)
+func (p *lfuPolicy) ageAfterInterval() {
+	if p.clock.Now().Sub(p.lastAging) >= p.agingInterval {
+		p.age()
 	}
 }
 
-func (c *LFUCache) keys() []interface{} {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
-	keys := make([]interface{}, len(c.items))
-	var i = 0
-	for k := range c.items {
-		keys[i] = k
-		i++
-	}
-	return keys
-}
+// age halves the frequency of every item. Entries whose frequencies become
+// equal are merged, and items with a frequency of 1 go back to 0.
+func (p *lfuPolicy) age() {
+	p.lastAging = p.clock.Now()
+	p.samples = 0
 
-// GetALL returns all key-value pairs in the cache.
-func (c *LFUCache) GetALL(checkExpired bool) map[interface{}]interface{} {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
-	items := make(map[interface{}]interface{}, len(c.items))
-	now := time.Now()
-	for k, item := range c.items {
-		if !checkExpired || c.has(k, &now) {
-			items[k] = item.value
-		}
-	}
-	return items
-}
-
-// Keys returns a slice of the keys in the cache.
-func (c *LFUCache) Keys(checkExpired bool) []interface{} {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
-	keys := make([]interface{}, 0, len(c.items))
-	now := time.Now()
-	for k := range c.items {
-		if !checkExpired || c.has(k, &now) {
-			keys = append(keys, k)
-		}
-	}
-	return keys
-}
-
-// Len returns the number of items in the cache.
-func (c *LFUCache) Len(checkExpired bool) int {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
-	if !checkExpired {
-		return len(c.items)
-	}
-	var length int
-	now := time.Now()
-	for k := range c.items {
-		if c.has(k, &now) {
-			length++
-		}
-	}
-	return length
-}
-
-// Completely clear the cache
-func (c *LFUCache) Purge() {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-
-	if c.purgeVisitorFunc != nil {
-		for key, item := range c.items {
-			c.purgeVisitorFunc(key, item.value)
+	prev := p.freqList.Front()
+	for e := prev.Next(); e != nil; {
+		next := e.Next()
+		entry := e.Value.(*freqEntry)
+		entry.freq /= 2
+		if prevEntry := prev.Value.(*freqEntry); prevEntry.freq == entry.freq {
+			for item := range entry.items {
+				prevEntry.items[item] = struct{}{}
+				item.freqElement = prev
+			}
+			p.freqList.Remove(e)
+		} else {
+			prev = e
 		}
+		e = next
 	}
-
-	c.init()
-}
-
-// IsExpired returns boolean value whether this item is expired or not.
-func (it *lfuItem) IsExpired(now *time.Time) bool {
-	if it.expiration == nil {
-		return false
-	}
-	if now == nil {
-		t := it.clock.Now()
-		now = &t
-	}
-	return it.expiration.Before(*now)
 }
 
 func isRemovableFreqEntry(entry *freqEntry) bool {
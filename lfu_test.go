@@ -92,11 +92,11 @@ func TestLFUFreqListOrder(t *testing.T) {
 			gc.Get(i)
 		}
 	}
-	if l := gc.(*LFUCache).freqList.Len(); l != 6 {
+	if l := lfuFreqListLen(gc); l != 6 {
 		t.Fatalf("%v != 6", l)
 	}
 	var i uint
-	for e := gc.(*LFUCache).freqList.Front(); e != nil; e = e.Next() {
+	for e := gc.(*LFUCache).policy.(*lfuPolicy).freqList.Front(); e != nil; e = e.Next() {
 		if e.Value.(*freqEntry).freq != i {
 			t.Fatalf("%v != %v", e.Value.(*freqEntry).freq, i)
 		}
@@ -104,19 +104,19 @@ func TestLFUFreqListOrder(t *testing.T) {
 	}
 	gc.Remove(1)
 
-	if l := gc.(*LFUCache).freqList.Len(); l != 5 {
+	if l := lfuFreqListLen(gc); l != 5 {
 		t.Fatalf("%v != 5", l)
 	}
 	gc.Set(1, 1)
-	if l := gc.(*LFUCache).freqList.Len(); l != 5 {
+	if l := lfuFreqListLen(gc); l != 5 {
 		t.Fatalf("%v != 5", l)
 	}
 	gc.Get(1)
-	if l := gc.(*LFUCache).freqList.Len(); l != 5 {
+	if l := lfuFreqListLen(gc); l != 5 {
 		t.Fatalf("%v != 5", l)
 	}
 	gc.Get(1)
-	if l := gc.(*LFUCache).freqList.Len(); l != 6 {
+	if l := lfuFreqListLen(gc); l != 6 {
 		t.Fatalf("%v != 6", l)
 	}
 }
@@ -127,7 +127,7 @@ func TestLFUFreqListLength(t *testing.T) {
 
 	{
 		gc := buildTestCache(t, TYPE_LFU, 5)
-		if l := gc.(*LFUCache).freqList.Len(); l != 1 {
+		if l := lfuFreqListLen(gc); l != 1 {
 			t.Fatalf("%v != 1", l)
 		}
 	}
@@ -137,7 +137,7 @@ func TestLFUFreqListLength(t *testing.T) {
 		for i := 0; i < 5; i++ {
 			gc.Get(k0)
 		}
-		if l := gc.(*LFUCache).freqList.Len(); l != 2 {
+		if l := lfuFreqListLen(gc); l != 2 {
 			t.Fatalf("%v != 2", l)
 		}
 	}
@@ -150,7 +150,7 @@ func TestLFUFreqListLength(t *testing.T) {
 			gc.Get(k0)
 			gc.Get(k1)
 		}
-		if l := gc.(*LFUCache).freqList.Len(); l != 2 {
+		if l := lfuFreqListLen(gc); l != 2 {
 			t.Fatalf("%v != 2", l)
 		}
 	}
@@ -162,13 +162,13 @@ func TestLFUFreqListLength(t *testing.T) {
 		for i := 0; i < 5; i++ {
 			gc.Get(k0)
 		}
-		if l := gc.(*LFUCache).freqList.Len(); l != 2 {
+		if l := lfuFreqListLen(gc); l != 2 {
 			t.Fatalf("%v != 2", l)
 		}
 		for i := 0; i < 5; i++ {
 			gc.Get(k1)
 		}
-		if l := gc.(*LFUCache).freqList.Len(); l != 2 {
+		if l := lfuFreqListLen(gc); l != 2 {
 			t.Fatalf("%v != 2", l)
 		}
 	}
@@ -177,20 +177,142 @@ func TestLFUFreqListLength(t *testing.T) {
 		gc := buildTestCache(t, TYPE_LFU, 5)
 		gc.Set(k0, v0)
 		gc.Get(k0)
-		if l := gc.(*LFUCache).freqList.Len(); l != 2 {
+		if l := lfuFreqListLen(gc); l != 2 {
 			t.Fatalf("%v != 2", l)
 		}
 		gc.Remove(k0)
-		if l := gc.(*LFUCache).freqList.Len(); l != 1 {
+		if l := lfuFreqListLen(gc); l != 1 {
 			t.Fatalf("%v != 1", l)
 		}
 		gc.Set(k0, v0)
-		if l := gc.(*LFUCache).freqList.Len(); l != 1 {
+		if l := lfuFreqListLen(gc); l != 1 {
 			t.Fatalf("%v != 1", l)
 		}
 		gc.Get(k0)
-		if l := gc.(*LFUCache).freqList.Len(); l != 2 {
+		if l := lfuFreqListLen(gc); l != 2 {
 			t.Fatalf("%v != 2", l)
 		}
 	}
 }
+
+func TestLFUMaxFrequency(t *testing.T) {
+	size := 2
+	gc := New(size).
+		LFU().
+		LFUMaxFrequency(3).
+		Build()
+
+	gc.Set(0, 0)
+	for i := 0; i < 100; i++ {
+		gc.Get(0)
+	}
+	if l := lfuFreqListLen(gc); l != 2 {
+		t.Fatalf("%v != 2", l)
+	}
+	if f := gc.(*LFUCache).policy.(*lfuPolicy).items[0].freqElement.Value.(*freqEntry).freq; f != 3 {
+		t.Fatalf("frequency should be capped at 3, but got %v", f)
+	}
+}
+
+func TestLFUAgingSamples(t *testing.T) {
+	size := 3
+	gc := New(size).
+		LFU().
+		LFUAgingSamples(10).
+		Build()
+
+	// 0 was hot in the past
+	gc.Set(0, 0)
+	for i := 0; i < 9; i++ {
+		gc.Get(0)
+	}
+	gc.Set(1, 1)
+	gc.Get(1)
+	// the 10th access halves the frequencies: 9 -> 4, 1 -> 0
+	lfuFreqListLen(gc)
+	c := gc.(*LFUCache).policy.(*lfuPolicy)
+	if f := c.items[0].freqElement.Value.(*freqEntry).freq; f != 4 {
+		t.Fatalf("%v != 4", f)
+	}
+	if f := c.items[1].freqElement.Value.(*freqEntry).freq; f != 0 {
+		t.Fatalf("%v != 0", f)
+	}
+	testLFUFreqList(t, c)
+
+	// recent accesses now outweigh the old ones
+	gc.Set(2, 2)
+	for i := 0; i < 9; i++ {
+		gc.Get(1)
+		gc.Get(2)
+	}
+	gc.Set(3, 3)
+	if gc.Has(0) {
+		t.Fatal("0 should be evicted")
+	}
+}
+
+func TestLFUAgingInterval(t *testing.T) {
+	clock := NewFakeClock()
+	gc := New(3).
+		LFU().
+		Clock(clock).
+		LFUAgingInterval(time.Minute).
+		Build()
+
+	gc.Set(0, 0)
+	for i := 0; i < 8; i++ {
+		gc.Get(0)
+	}
+	gc.Set(1, 1)
+	lfuFreqListLen(gc)
+	c := gc.(*LFUCache).policy.(*lfuPolicy)
+	if f := c.items[0].freqElement.Value.(*freqEntry).freq; f != 8 {
+		t.Fatalf("%v != 8", f)
+	}
+
+	for i := 0; i < 3; i++ {
+		clock.Advance(time.Minute)
+		gc.Set(1, 1)
+	}
+	if f := c.items[0].freqElement.Value.(*freqEntry).freq; f != 1 {
+		t.Fatalf("%v != 1", f)
+	}
+	testLFUFreqList(t, c)
+}
+
+// testLFUFreqList checks that the frequency list starts with 0, is strictly
+// increasing, and that every item points at its entry.
+func testLFUFreqList(t *testing.T, c *lfuPolicy) {
+	t.Helper()
+	var prev *freqEntry
+	var n int
+	for e := c.freqList.Front(); e != nil; e = e.Next() {
+		entry := e.Value.(*freqEntry)
+		if prev == nil && entry.freq != 0 {
+			t.Fatalf("first frequency should be 0, but got %v", entry.freq)
+		}
+		if prev != nil && entry.freq <= prev.freq {
+			t.Fatalf("%v should be greater than %v", entry.freq, prev.freq)
+		}
+		for item := range entry.items {
+			if item.freqElement != e {
+				t.Fatalf("%v points at the wrong entry", item.key)
+			}
+			n++
+		}
+		prev = entry
+	}
+	if n != len(c.items) {
+		t.Fatalf("%v items in the list, %v indexed", n, len(c.items))
+	}
+}
+
+// lfuFreqListLen replays the buffered hits and returns the length of the
+// frequency list.
+func lfuFreqListLen(gc Cache) int {
+	c := gc.(*LFUCache)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.flushReads()
+	return c.policy.(*lfuPolicy).freqList.Len()
+}
@@ -0,0 +1,260 @@
+package gcache
+
+import (
+	"container/list"
+)
+
+// LIRSCache implements the LIRS (Low Inter-reference Recency Set) algorithm.
+// Items are ranked by the number of other keys seen between their last two
+// accesses instead of by the time of their last access. Most of the capacity
+// goes to LIR items, which were reused quickly; the rest holds HIR items,
+// which are evicted first. The stack S orders LIR items, resident HIR items
+// and recently evicted (non-resident) HIR keys by recency, and the queue Q
+// orders the resident HIR items for eviction. A key seen once in a scan or a
+// loop longer than the cache stays HIR, so it cannot push out the LIR items.
+type LIRSCache struct {
+	baseCache
+}
+
+var _ Cache = (*LIRSCache)(nil)
+
+func newLIRSCache(cb *CacheBuilder) *LIRSCache {
+	c := &LIRSCache{}
+	buildCache(&c.baseCache, cb, newLIRSPolicy())
+	return c
+}
+
+type lirsPolicy struct {
+	items   map[interface{}]*lirsItem // resident and non-resident items
+	stack   *list.List                // S, most recent at the front
+	queue   *list.List                // Q, next victim at the front
+	ghosts  *list.List                // non-resident items, oldest at the front
+	size    int
+	lirSize int
+	lirLen  int // number of LIR items
+	resLen  int // number of resident items
+}
+
+type lirsItem struct {
+	key      interface{}
+	lir      bool
+	resident bool
+	inStack  *list.Element
+	inQueue  *list.Element
+	inGhosts *list.Element
+}
+
+func newLIRSPolicy() *lirsPolicy {
+	p := &lirsPolicy{}
+	p.reset()
+	return p
+}
+
+func (p *lirsPolicy) reset() {
+	p.items = make(map[interface{}]*lirsItem)
+	p.stack = list.New()
+	p.queue = list.New()
+	p.ghosts = list.New()
+	p.lirLen = 0
+	p.resLen = 0
+}
+
+// SetSize gives 99% of the cache size to LIR items, keeping at least one
+// slot for HIR items unless the size is 1, and keeps at most size
+// non-resident items.
+func (p *lirsPolicy) SetSize(size int) {
+	p.size = size
+	p.lirSize = maxInt(1, size-maxInt(1, size/100))
+	p.demoteBottom()
+	p.trimGhosts()
+}
+
+func (p *lirsPolicy) OnInsert(key interface{}) {
+	item, ok := p.items[key]
+	if ok {
+		p.ghosts.Remove(item.inGhosts)
+		item.inGhosts = nil
+	} else {
+		item = &lirsItem{key: key}
+		p.items[key] = item
+	}
+	item.resident = true
+	p.resLen++
+	p.insert(item, ok)
+}
+
+func (p *lirsPolicy) OnAccess(key interface{}) {
+	if item, ok := p.items[key]; ok && item.resident {
+		p.access(item)
+	}
+}
+
+func (p *lirsPolicy) OnRemove(key interface{}) {
+	if item, ok := p.items[key]; ok && item.resident {
+		p.removeItem(item)
+	}
+}
+
+// Victim evicts the resident HIR item at the front of the queue. An item
+// still in the stack stays there as a non-resident item; at most size of
+// them are kept.
+func (p *lirsPolicy) Victim() (interface{}, bool) {
+	if p.resLen == 0 {
+		return nil, false
+	}
+	if p.queue.Len() == 0 {
+		// every resident item is LIR
+		p.demote()
+	}
+	if p.queue.Len() == 0 {
+		return nil, false
+	}
+	item := p.queue.Front().Value.(*lirsItem)
+	p.queue.Remove(item.inQueue)
+	item.inQueue = nil
+	p.resLen--
+	item.resident = false
+	if item.inStack == nil {
+		delete(p.items, item.key)
+	} else {
+		item.inGhosts = p.ghosts.PushBack(item)
+		p.trimGhosts()
+	}
+	return item.key, true
+}
+
+// insert places an item that just became resident. Until the LIR set is
+// full every item becomes LIR. After that an item becomes LIR only if it is
+// still in the stack as a non-resident item, which means that it was reused
+// before the least recent LIR item.
+func (p *lirsPolicy) insert(item *lirsItem, nonResident bool) {
+	switch {
+	case p.lirLen < p.lirSize:
+		item.lir = true
+		p.lirLen++
+		p.pushStack(item)
+		// the first LIR item may sit above HIR items
+		p.prune()
+	case nonResident:
+		item.lir = true
+		p.lirLen++
+		p.pushStack(item)
+		p.demoteBottom()
+	default:
+		p.pushStack(item)
+		item.inQueue = p.queue.PushBack(item)
+	}
+}
+
+// access records a hit on a resident item.
+// A HIR item still in the stack has a smaller reuse distance than the least
+// recent LIR item, so the two swap their status. A HIR item also becomes LIR
+// while the LIR set is not full, after demotions and removals.
+func (p *lirsPolicy) access(item *lirsItem) {
+	if item.lir {
+		bottom := item.inStack == p.stack.Back()
+		p.pushStack(item)
+		if bottom {
+			p.prune()
+		}
+		return
+	}
+	if item.inStack != nil || p.lirLen < p.lirSize {
+		p.pushStack(item)
+		p.queue.Remove(item.inQueue)
+		item.inQueue = nil
+		item.lir = true
+		p.lirLen++
+		p.demoteBottom()
+		p.prune()
+		return
+	}
+	p.pushStack(item)
+	p.queue.MoveToBack(item.inQueue)
+}
+
+func (p *lirsPolicy) pushStack(item *lirsItem) {
+	if item.inStack != nil {
+		p.stack.MoveToFront(item.inStack)
+	} else {
+		item.inStack = p.stack.PushFront(item)
+	}
+}
+
+// demoteBottom turns the least recent LIR items into resident HIR items
+// while there are more LIR items than allowed.
+func (p *lirsPolicy) demoteBottom() {
+	for p.lirLen > p.lirSize {
+		p.demote()
+	}
+}
+
+// demote turns the least recent LIR item into a resident HIR item at the
+// end of the queue. The HIR items below it are pruned first: they are only
+// there when the item just became the first LIR item.
+func (p *lirsPolicy) demote() {
+	p.prune()
+	e := p.stack.Back()
+	if e == nil {
+		return
+	}
+	bottom := e.Value.(*lirsItem)
+	p.stack.Remove(bottom.inStack)
+	bottom.inStack = nil
+	bottom.lir = false
+	p.lirLen--
+	bottom.inQueue = p.queue.PushBack(bottom)
+	p.prune()
+}
+
+// prune removes HIR items from the bottom of the stack until an LIR item is
+// at the bottom. Non-resident items leaving the stack are forgotten.
+func (p *lirsPolicy) prune() {
+	for e := p.stack.Back(); e != nil; e = p.stack.Back() {
+		item := e.Value.(*lirsItem)
+		if item.lir {
+			return
+		}
+		p.stack.Remove(e)
+		item.inStack = nil
+		if !item.resident {
+			p.forget(item)
+		}
+	}
+}
+
+// forget drops a non-resident item.
+func (p *lirsPolicy) forget(item *lirsItem) {
+	if item.inStack != nil {
+		p.stack.Remove(item.inStack)
+		item.inStack = nil
+	}
+	p.ghosts.Remove(item.inGhosts)
+	item.inGhosts = nil
+	delete(p.items, item.key)
+}
+
+func (p *lirsPolicy) trimGhosts() {
+	for p.ghosts.Len() > p.size {
+		p.forget(p.ghosts.Front().Value.(*lirsItem))
+	}
+}
+
+// removeItem removes a resident item without leaving a non-resident item
+// behind.
+func (p *lirsPolicy) removeItem(item *lirsItem) {
+	if item.inQueue != nil {
+		p.queue.Remove(item.inQueue)
+		item.inQueue = nil
+	}
+	if item.inStack != nil {
+		p.stack.Remove(item.inStack)
+		item.inStack = nil
+	}
+	if item.lir {
+		p.lirLen--
+	}
+	p.resLen--
+	delete(p.items, item.key)
+	p.prune()
+}
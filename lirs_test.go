@@ -0,0 +1,269 @@
+package gcache
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestLIRSGet(t *testing.T) {
+	size := 1000
+	gc := buildTestCache(t, TYPE_LIRS, size)
+	testSetCache(t, gc, size)
+	testGetCache(t, gc, size)
+}
+
+func TestLoadingLIRSGet(t *testing.T) {
+	size := 1000
+	numbers := 1000
+	testGetCache(t, buildTestLoadingCache(t, TYPE_LIRS, size, loader), numbers)
+}
+
+func TestLIRSLength(t *testing.T) {
+	clock := NewFakeClock()
+	gc := buildTestLoadingCacheWithClock(t, TYPE_LIRS, 2, time.Millisecond, clock)
+	gc.Get("test1")
+	gc.Get("test2")
+	gc.Get("test3")
+	length := gc.Len(true)
+	expectedLength := 2
+	if length != expectedLength {
+		t.Errorf("Expected length is %v, not %v", expectedLength, length)
+	}
+	clock.Advance(2 * time.Millisecond)
+	gc.Get("test4")
+	length = gc.Len(true)
+	expectedLength = 1
+	if length != expectedLength {
+		t.Errorf("Expected length is %v, not %v", expectedLength, length)
+	}
+}
+
+func TestLIRSEvictItem(t *testing.T) {
+	cacheSize := 10
+	numbers := cacheSize + 1
+	gc := buildTestLoadingCache(t, TYPE_LIRS, cacheSize, loader)
+
+	for i := 0; i < numbers; i++ {
+		_, err := gc.Get(fmt.Sprintf("Key-%d", i))
+		if err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+	}
+	if l := gc.Len(false); l != cacheSize {
+		t.Errorf("Expected length is %v, not %v", cacheSize, l)
+	}
+}
+
+func TestLIRSPurgeCache(t *testing.T) {
+	cacheSize := 10
+	purgeCount := 0
+	gc := New(cacheSize).
+		LIRS().
+		LoaderFunc(loader).
+		PurgeVisitorFunc(func(k, v interface{}) {
+			purgeCount++
+		}).
+		Build()
+
+	for i := 0; i < cacheSize; i++ {
+		_, err := gc.Get(fmt.Sprintf("Key-%d", i))
+		if err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+	}
+
+	gc.Purge()
+
+	if purgeCount != cacheSize {
+		t.Errorf("failed to purge everything")
+	}
+}
+
+func TestLIRSGetIFPresent(t *testing.T) {
+	testGetIFPresent(t, TYPE_LIRS)
+}
+
+func TestLIRSHas(t *testing.T) {
+	gc := buildTestLoadingCacheWithExpiration(t, TYPE_LIRS, 2, 10*time.Millisecond)
+
+	for i := 0; i < 10; i++ {
+		t.Run(fmt.Sprint(i), func(t *testing.T) {
+			gc.Get("test1")
+			gc.Get("test2")
+
+			if gc.Has("test0") {
+				t.Fatal("should not have test0")
+			}
+			if !gc.Has("test1") {
+				t.Fatal("should have test1")
+			}
+			if !gc.Has("test2") {
+				t.Fatal("should have test2")
+			}
+
+			time.Sleep(20 * time.Millisecond)
+
+			if gc.Has("test0") {
+				t.Fatal("should not have test0")
+			}
+			if gc.Has("test1") {
+				t.Fatal("should not have test1")
+			}
+			if gc.Has("test2") {
+				t.Fatal("should not have test2")
+			}
+		})
+	}
+}
+
+func TestLIRSStatusSwap(t *testing.T) {
+	size := 4
+	gc := buildTestCache(t, TYPE_LIRS, size).(*LIRSCache)
+	p := gc.policy.(*lirsPolicy)
+
+	// the first lirSize items become LIR, the next one is HIR
+	setItemsByRange(t, gc, 0, size)
+	if !p.items[0].lir || p.items[3].lir {
+		t.Fatal("0 should be LIR and 3 should be HIR")
+	}
+	// 4 evicts the HIR item 3, which stays in the stack as non-resident
+	setItemsByRange(t, gc, 4, 5)
+	if gc.Has(3) {
+		t.Fatal("3 should be evicted")
+	}
+	if item, ok := p.items[3]; !ok || item.resident {
+		t.Fatal("3 should be kept as a non-resident item")
+	}
+	// 3 is reused before the least recent LIR item 0, so they swap status
+	setItemsByRange(t, gc, 3, 4)
+	if !p.items[3].lir {
+		t.Fatal("3 should become LIR")
+	}
+	if p.items[0].lir {
+		t.Fatal("0 should become HIR")
+	}
+	if l := gc.Len(false); l != size {
+		t.Fatalf("%v != %v", l, size)
+	}
+}
+
+func TestLIRSScanResistance(t *testing.T) {
+	size := 100
+	hot := 20
+	gc := buildTestCache(t, TYPE_LIRS, size)
+
+	setItemsByRange(t, gc, 0, hot)
+	for i := 0; i < hot; i++ {
+		gc.Get(i)
+	}
+
+	// a long sequential scan only churns the HIR items
+	setItemsByRange(t, gc, 10000, 10000+10*size)
+	for i := 0; i < hot; i++ {
+		if _, err := gc.Get(i); err != nil {
+			t.Fatalf("hot key %v should survive the scan: %v", i, err)
+		}
+	}
+
+	lru := buildTestCache(t, TYPE_LRU, size)
+	setItemsByRange(t, lru, 0, hot)
+	setItemsByRange(t, lru, 10000, 10000+10*size)
+	if lru.Has(0) {
+		t.Fatal("LRU should lose the hot keys to the scan")
+	}
+}
+
+func TestLIRSLoop(t *testing.T) {
+	size := 100
+	loop := size + size/2
+
+	hits := func(tp string) int {
+		gc := buildTestCache(t, tp, size)
+		for i := 0; i < 10*loop; i++ {
+			key := i % loop
+			if _, err := gc.Get(key); err != nil {
+				gc.Set(key, key)
+			}
+		}
+		return int(gc.HitCount())
+	}
+	// LRU always evicts the key that is needed next
+	if n := hits(TYPE_LRU); n != 0 {
+		t.Fatalf("LRU should not hit a loop larger than the cache, got %v hits", n)
+	}
+	if n := hits(TYPE_LIRS); n < 5*size {
+		t.Fatalf("LIRS should keep most of the LIR items in the loop, got %v hits", n)
+	}
+}
+
+func TestLIRSInvariants(t *testing.T) {
+	for _, size := range []int{1, 2, 3, 7, 32} {
+		t.Run(fmt.Sprint(size), func(t *testing.T) {
+			testLIRSInvariants(t, size, false)
+		})
+	}
+}
+
+func TestLIRSInvariantsShrinking(t *testing.T) {
+	testLIRSInvariants(t, 32, true)
+}
+
+func testLIRSInvariants(t *testing.T, size int, shrink bool) {
+	gc := buildTestCache(t, TYPE_LIRS, size).(*LIRSCache)
+	p := gc.policy.(*lirsPolicy)
+
+	seed := uint32(1)
+	next := func() int {
+		seed = seed*1664525 + 1013904223
+		return int(seed>>16) % (4 * size)
+	}
+	for i := 0; i < 20000; i++ {
+		if shrink && i%1000 == 999 && size > 1 {
+			size--
+			gc.mu.Lock()
+			gc.resize(size)
+			gc.mu.Unlock()
+		}
+		key := next()
+		switch i % 7 {
+		case 0:
+			gc.Remove(key)
+		case 1, 2:
+			gc.Get(key)
+		default:
+			gc.Set(key, key)
+		}
+
+		if p.resLen > size {
+			t.Fatalf("%v resident items exceed the size %v", p.resLen, size)
+		}
+		if n := len(gc.Keys(false)); n > size {
+			t.Fatalf("%v keys exceed the size %v", n, size)
+		}
+		if p.lirLen > p.lirSize {
+			t.Fatalf("%v LIR items exceed %v", p.lirLen, p.lirSize)
+		}
+		if n := p.ghosts.Len(); n > size {
+			t.Fatalf("%v non-resident items exceed the size %v", n, size)
+		}
+		if n := len(p.items); n != p.resLen+p.ghosts.Len() {
+			t.Fatalf("%v items indexed, %v counted", n, p.resLen+p.ghosts.Len())
+		}
+		if n := len(gc.Keys(false)); n != p.resLen {
+			t.Fatalf("%v keys, %v resident items", n, p.resLen)
+		}
+		for e := p.queue.Front(); e != nil; e = e.Next() {
+			if item := e.Value.(*lirsItem); item.lir || !item.resident {
+				t.Fatalf("the queue holds %v, which is LIR or not resident", item.key)
+			}
+		}
+		if e := p.stack.Back(); e != nil && !e.Value.(*lirsItem).lir {
+			t.Fatal("the bottom of the stack should be an LIR item")
+		}
+	}
+}
+
+func TestLIRSConcurrentGet(t *testing.T) {
+	testConcurrentGet(t, TYPE_LIRS)
+}
@@ -0,0 +1,74 @@
+package gcache
+
+import (
+	"time"
+)
+
+const (
+	// defaultMemoryCheckInterval is how often the heap is sampled when
+	// SoftMemoryLimit is set and no interval was given.
+	defaultMemoryCheckInterval = time.Second
+	// memoryLowWater is the fraction of the soft limit the heap has to drop
+	// below before a shrunk cache is allowed to grow again.
+	memoryLowWater = 0.9
+)
+
+// MemoryReader reports the number of heap bytes currently in use.
+type MemoryReader func() uint64
+
+// memoryController watches heap usage against a soft limit and decides
+// how many entries a cache may hold. While the heap is over the limit the
+// capacity shrinks by a quarter on every check, and once the heap is back
+// under the low-water mark it grows again in steps of an eighth of the
+// configured size.
+type memoryController struct {
+	reader    MemoryReader
+	limit     uint64
+	interval  time.Duration
+	maxSize   int
+	lastCheck time.Time
+	*stats
+}
+
+func newMemoryController(cb *CacheBuilder, st *stats) *memoryController {
+	if cb.memoryLimit == 0 || cb.size <= 0 {
+		return nil
+	}
+	mc := &memoryController{
+		reader:   cb.memoryReader,
+		limit:    cb.memoryLimit,
+		interval: cb.memoryCheckInterval,
+		maxSize:  cb.size,
+		stats:    st,
+	}
+	if mc.reader == nil {
+		mc.reader = readHeapInUse
+	}
+	if mc.interval <= 0 {
+		mc.interval = defaultMemoryCheckInterval
+	}
+	return mc
+}
+
+// check samples the heap if the check interval has passed and calls resize
+// with the new capacity when it changes. size is the current capacity.
+func (mc *memoryController) check(now time.Time, size int, resize func(int)) {
+	if !mc.lastCheck.IsZero() && now.Sub(mc.lastCheck) < mc.interval {
+		return
+	}
+	mc.lastCheck = now
+
+	inUse := mc.reader()
+	switch {
+	case inUse > mc.limit && size > 1:
+		newSize := maxInt(1, size-maxInt(1, size/4))
+		mc.stats.IncrShrinkCount()
+		mc.stats.setCapacity(newSize)
+		resize(newSize)
+	case float64(inUse) < float64(mc.limit)*memoryLowWater && size < mc.maxSize:
+		newSize := minInt(mc.maxSize, size+maxInt(1, mc.maxSize/8))
+		mc.stats.IncrGrowCount()
+		mc.stats.setCapacity(newSize)
+		resize(newSize)
+	}
+}
@@ -0,0 +1,16 @@
+//go:build !go1.16
+// +build !go1.16
+
+package gcache
+
+import (
+	"runtime"
+)
+
+// readHeapInUse returns the bytes of allocated heap objects. runtime/metrics
+// is not available before Go 1.16, so this falls back to ReadMemStats.
+func readHeapInUse() uint64 {
+	var ms runtime.MemStats
+	runtime.ReadMemStats(&ms)
+	return ms.HeapAlloc
+}
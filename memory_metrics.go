@@ -0,0 +1,21 @@
+//go:build go1.16
+// +build go1.16
+
+package gcache
+
+import (
+	"runtime/metrics"
+)
+
+const heapObjectsMetric = "/memory/classes/heap/objects:bytes"
+
+// readHeapInUse returns the bytes occupied by heap objects, live or not yet
+// swept, as reported by runtime/metrics.
+func readHeapInUse() uint64 {
+	sample := []metrics.Sample{{Name: heapObjectsMetric}}
+	metrics.Read(sample)
+	if sample[0].Value.Kind() != metrics.KindUint64 {
+		return 0
+	}
+	return sample[0].Value.Uint64()
+}
@@ -0,0 +1,123 @@
+package gcache
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestSoftMemoryLimit(t *testing.T) {
+	var tps = []string{
+		TYPE_SIMPLE,
+		TYPE_LRU,
+		TYPE_LFU,
+		TYPE_ARC,
+		TYPE_2Q,
+		TYPE_SIEVE,
+		TYPE_S3FIFO,
+		TYPE_CLOCK,
+		TYPE_CLOCK_PRO,
+		TYPE_LIRS,
+		TYPE_GDSF,
+		TYPE_ADAPTIVE,
+	}
+	for _, tp := range tps {
+		t.Run(tp, func(t *testing.T) {
+			size := 100
+			var heap uint64 = 500
+			var evicted int
+			clock := NewFakeClock()
+			gc := New(size).
+				EvictType(tp).
+				Clock(clock).
+				SoftMemoryLimit(1000).
+				MemoryCheckInterval(time.Second).
+				MemoryReader(func() uint64 {
+					return atomic.LoadUint64(&heap)
+				}).
+				EvictedFunc(func(key, value interface{}) {
+					evicted++
+				}).
+				Build()
+
+			setItemsByRange(t, gc, 0, size)
+			if l := gc.Len(false); l != size {
+				t.Fatalf("%v != %v", l, size)
+			}
+
+			atomic.StoreUint64(&heap, 2000)
+			clock.Advance(time.Second)
+			setItemsByRange(t, gc, size, size+1)
+			if c := gc.Capacity(); c != 75 {
+				t.Fatalf("capacity should be 75, but got %v", c)
+			}
+			if l := gc.Len(false); l != 75 {
+				t.Fatalf("%v != %v", l, 75)
+			}
+			if evicted != 26 {
+				t.Fatalf("%v items should be evicted, but got %v", 26, evicted)
+			}
+			if n := gc.ShrinkCount(); n != 1 {
+				t.Fatalf("%v != %v", n, 1)
+			}
+
+			// no new decision until the interval has passed
+			setItemsByRange(t, gc, size+1, size+2)
+			if n := gc.ShrinkCount(); n != 1 {
+				t.Fatalf("%v != %v", n, 1)
+			}
+
+			atomic.StoreUint64(&heap, 950)
+			clock.Advance(time.Second)
+			setItemsByRange(t, gc, size+2, size+3)
+			if n := gc.ShrinkCount() + gc.GrowCount(); n != 1 {
+				t.Fatalf("heap between low water and limit should keep the capacity, got %v decisions", n)
+			}
+
+			atomic.StoreUint64(&heap, 100)
+			clock.Advance(time.Second)
+			setItemsByRange(t, gc, size+3, size+4)
+			if c := gc.Capacity(); c != 87 {
+				t.Fatalf("capacity should be 87, but got %v", c)
+			}
+			if n := gc.GrowCount(); n != 1 {
+				t.Fatalf("%v != %v", n, 1)
+			}
+
+			for i := 0; i < 2; i++ {
+				clock.Advance(time.Second)
+				setItemsByRange(t, gc, size+4+i, size+5+i)
+			}
+			if c := gc.Capacity(); c != size {
+				t.Fatalf("capacity should grow back to %v, but got %v", size, c)
+			}
+			setItemsByRange(t, gc, 0, 2*size)
+			if l := gc.Len(false); l != size {
+				t.Fatalf("%v != %v", l, size)
+			}
+		})
+	}
+}
+
+func TestSoftMemoryLimitMinimumCapacity(t *testing.T) {
+	clock := NewFakeClock()
+	gc := New(4).
+		LRU().
+		Clock(clock).
+		SoftMemoryLimit(1).
+		MemoryReader(func() uint64 {
+			return 2
+		}).
+		Build()
+
+	for i := 0; i < 10; i++ {
+		clock.Advance(time.Second)
+		setItemsByRange(t, gc, i, i+1)
+	}
+	if c := gc.Capacity(); c != 1 {
+		t.Fatalf("capacity should not drop below 1, but got %v", c)
+	}
+	if v, err := gc.Get(9); err != nil || v != 9 {
+		t.Fatalf("the latest item should be kept: %v, %v", v, err)
+	}
+}
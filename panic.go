@@ -0,0 +1,25 @@
+package gcache
+
+import "fmt"
+
+// LoaderPanicError is the error returned by Get when the loader panics.
+type LoaderPanicError struct {
+	Key interface{}
+	// Value is the value passed to panic.
+	Value interface{}
+	// Stack is the stack of the goroutine that panicked.
+	Stack []byte
+}
+
+func (e *LoaderPanicError) Error() string {
+	return fmt.Sprintf("Loader panics: %v", e.Value)
+}
+
+// LoaderPanicFunc is called with every panic of the loader, in the
+// goroutine that called the loader.
+type LoaderPanicFunc func(*LoaderPanicError)
+
+// Is reports whether target is ErrLoaderFailed.
+func (e *LoaderPanicError) Is(target error) bool {
+	return target == ErrLoaderFailed
+}
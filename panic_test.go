@@ -0,0 +1,81 @@
+package gcache
+
+import (
+	"bytes"
+	"sync/atomic"
+	"testing"
+
+	"github.com/bluele/gcache/singleflight"
+)
+
+func TestLoaderPanicError(t *testing.T) {
+	var hooked int32
+	gc := New(10).
+		LRU().
+		LoaderFunc(func(key interface{}) (interface{}, error) {
+			panic("boom")
+		}).
+		LoaderPanicFunc(func(err *LoaderPanicError) {
+			atomic.AddInt32(&hooked, 1)
+		}).
+		Build()
+	_, err := gc.Get("key")
+	perr, ok := err.(*LoaderPanicError)
+	if !ok {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if perr.Key != "key" || perr.Value != "boom" {
+		t.Errorf("unexpected key %v and value %v", perr.Key, perr.Value)
+	}
+	if !bytes.Contains(perr.Stack, []byte("TestLoaderPanicError")) {
+		t.Errorf("the stack does not contain the caller:\n%s", perr.Stack)
+	}
+	if n := atomic.LoadInt32(&hooked); n != 1 {
+		t.Errorf("the panic func is called %v times, expected 1", n)
+	}
+}
+
+func TestRepanicOnLoaderPanic(t *testing.T) {
+	gc := New(10).
+		LRU().
+		LoaderFunc(func(key interface{}) (interface{}, error) {
+			panic("boom")
+		}).
+		RepanicOnLoaderPanic().
+		Build()
+	defer func() {
+		r := recover()
+		if perr, ok := r.(*LoaderPanicError); !ok || perr.Value != "boom" {
+			t.Errorf("unexpected panic %v", r)
+		}
+	}()
+	gc.Get("key")
+	t.Error("Get does not panic")
+}
+
+func TestHookPanicIsNotLoaderPanic(t *testing.T) {
+	var hooked int32
+	gc := New(10).
+		LRU().
+		LoaderFunc(func(key interface{}) (interface{}, error) {
+			return key, nil
+		}).
+		SerializeFunc(func(key, value interface{}) (interface{}, error) {
+			panic("boom")
+		}).
+		LoaderPanicFunc(func(err *LoaderPanicError) {
+			atomic.AddInt32(&hooked, 1)
+		}).
+		Build()
+	defer func() {
+		r := recover()
+		if perr, ok := r.(*singleflight.PanicError); !ok || perr.Value != "boom" {
+			t.Errorf("unexpected panic %v", r)
+		}
+		if n := atomic.LoadInt32(&hooked); n != 0 {
+			t.Errorf("the panic func is called %v times, expected 0", n)
+		}
+	}()
+	gc.Get("key")
+	t.Error("Get does not panic")
+}
@@ -0,0 +1,135 @@
+package gcache
+
+import "sync/atomic"
+
+// Policy decides which entries a cache evicts.
+//
+// The cache keeps the entries and takes care of loading, expiration,
+// callbacks and statistics; the policy only tracks keys. Its methods are
+// called with the cache lock held, so it needs no locking of its own. Hits
+// are buffered and passed to OnAccess in batches, and may be dropped when
+// many goroutines read at once. The built-in policies that only mark a hit,
+// such as SIEVE and CLOCK, get their marks set at once instead.
+type Policy interface {
+	// OnInsert is called after key was added to the cache.
+	OnInsert(key interface{})
+	// OnAccess is called after key was read, and after it was updated
+	// unless the policy implements UpdatePolicy.
+	OnAccess(key interface{})
+	// OnRemove is called after key was removed from the cache by Remove,
+	// by expiring or by Purge.
+	OnRemove(key interface{})
+	// Victim is called when the cache is full, before OnInsert for a new
+	// key, and while the cache holds more than its size. It returns the key
+	// to evict and forgets about it, or false if there is nothing to evict.
+	// If it returns the key that is being updated, the key stays in the
+	// cache and is passed to OnInsert again.
+	Victim() (key interface{}, ok bool)
+}
+
+// SizedPolicy is implemented by policies that depend on the size of the
+// cache.
+type SizedPolicy interface {
+	Policy
+	// SetSize is called before the policy is used, and again every time
+	// the cache is resized, after the entries that no longer fit have been
+	// evicted.
+	SetSize(size int)
+}
+
+// UpdatePolicy is implemented by policies that do not treat an update of a
+// key like a read of it.
+type UpdatePolicy interface {
+	Policy
+	// OnUpdate is called instead of OnAccess after the value of key was
+	// replaced.
+	OnUpdate(key interface{})
+}
+
+// weightedPolicy is implemented by policies that take the weight and the
+// load cost of entries into account. setWeight is called after OnInsert,
+// OnAccess or OnUpdate for every write; a cost of 0 means that the value
+// was not loaded.
+type weightedPolicy interface {
+	setWeight(key interface{}, weight int, cost float64)
+}
+
+// resettablePolicy is implemented by policies that can forget every key at
+// once when the cache is purged.
+type resettablePolicy interface {
+	reset()
+}
+
+// inspectablePolicy is implemented by policies that keep metadata about a
+// key worth reporting by GetEntry.
+type inspectablePolicy interface {
+	inspect(key interface{}, e *Entry)
+}
+
+// markingPolicy is implemented by policies for which a hit only raises a
+// small counter kept for the key, such as a reference bit. Reads raise it
+// atomically without the cache lock, instead of buffering the hit for
+// OnAccess. mark is called with the lock held after every write of key.
+type markingPolicy interface {
+	mark(key interface{}) *accessMark
+}
+
+// accessMark counts the hits on a key up to max. Readers raise it without
+// the cache lock, so the policy must only use its methods.
+type accessMark struct {
+	n   uint32
+	max uint32
+}
+
+// hit raises the count unless it is at max.
+func (m *accessMark) hit() {
+	for {
+		n := atomic.LoadUint32(&m.n)
+		if n >= m.max || atomic.CompareAndSwapUint32(&m.n, n, n+1) {
+			return
+		}
+	}
+}
+
+// count returns the number of hits.
+func (m *accessMark) count() uint32 {
+	return atomic.LoadUint32(&m.n)
+}
+
+// clear forgets the hits.
+func (m *accessMark) clear() {
+	atomic.StoreUint32(&m.n, 0)
+}
+
+// decrement lowers the count by one, and returns false if it was 0.
+func (m *accessMark) decrement() bool {
+	for {
+		n := atomic.LoadUint32(&m.n)
+		if n == 0 {
+			return false
+		}
+		if atomic.CompareAndSwapUint32(&m.n, n, n-1) {
+			return true
+		}
+	}
+}
+
+// orderedPolicy is implemented by policies that can list their keys in
+// eviction order, from the next victim to the key they would evict last.
+type orderedPolicy interface {
+	order() []interface{}
+}
+
+// PolicyCache evicts the entries chosen by a Policy given to
+// CacheBuilder.Policy.
+type PolicyCache struct {
+	baseCache
+}
+
+var _ Cache = (*PolicyCache)(nil)
+
+func newPolicyCache(cb *CacheBuilder) *PolicyCache {
+	c := &PolicyCache{}
+	buildCache(&c.baseCache, cb, cb.policy())
+	return c
+}
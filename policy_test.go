@@ -0,0 +1,177 @@
+package gcache
+
+import (
+	"container/list"
+	"testing"
+)
+
+// fifoPolicy evicts keys in insertion order and records the calls it gets.
+type fifoPolicy struct {
+	keys     *list.List
+	elts     map[interface{}]*list.Element
+	accessed map[interface{}]int
+	removed  []interface{}
+}
+
+func newFIFOPolicy() *fifoPolicy {
+	return &fifoPolicy{
+		keys:     list.New(),
+		elts:     make(map[interface{}]*list.Element),
+		accessed: make(map[interface{}]int),
+	}
+}
+
+func (p *fifoPolicy) OnInsert(key interface{}) {
+	p.elts[key] = p.keys.PushBack(key)
+}
+
+func (p *fifoPolicy) OnAccess(key interface{}) {
+	p.accessed[key]++
+}
+
+func (p *fifoPolicy) OnRemove(key interface{}) {
+	p.removed = append(p.removed, key)
+	p.keys.Remove(p.elts[key])
+	delete(p.elts, key)
+}
+
+func (p *fifoPolicy) Victim() (interface{}, bool) {
+	e := p.keys.Front()
+	if e == nil {
+		return nil, false
+	}
+	p.keys.Remove(e)
+	delete(p.elts, e.Value)
+	return e.Value, true
+}
+
+func TestPolicyEviction(t *testing.T) {
+	size := 3
+	p := newFIFOPolicy()
+	var evicted []interface{}
+	gc := New(size).
+		Policy(func() Policy { return p }).
+		EvictedFunc(func(key, value interface{}) {
+			evicted = append(evicted, key)
+		}).
+		Build()
+	if _, ok := gc.(*PolicyCache); !ok {
+		t.Fatalf("expected a PolicyCache, got %T", gc)
+	}
+
+	setItemsByRange(t, gc, 0, size)
+	// reads do not change the order of a FIFO
+	gc.Get(0)
+	setItemsByRange(t, gc, size, size+2)
+	if len(evicted) != 2 || evicted[0] != 0 || evicted[1] != 1 {
+		t.Fatalf("0 and 1 should be evicted in order, got %v", evicted)
+	}
+	if p.accessed[0] != 1 {
+		t.Fatalf("0 should be accessed once, got %v", p.accessed[0])
+	}
+	if l := gc.Len(false); l != size {
+		t.Fatalf("%v != %v", l, size)
+	}
+	if p.keys.Len() != size {
+		t.Fatalf("policy tracks %v keys, expected %v", p.keys.Len(), size)
+	}
+}
+
+func TestPolicyRemove(t *testing.T) {
+	p := newFIFOPolicy()
+	gc := New(10).Policy(func() Policy { return p }).Build()
+
+	setItemsByRange(t, gc, 0, 5)
+	gc.Remove(2)
+	if len(p.removed) != 1 || p.removed[0] != 2 {
+		t.Fatalf("2 should be removed, got %v", p.removed)
+	}
+	// a policy without a reset hears about every purged key
+	gc.Purge()
+	if len(p.removed) != 5 {
+		t.Fatalf("%v keys removed, expected 5", len(p.removed))
+	}
+	if p.keys.Len() != 0 {
+		t.Fatalf("policy still tracks %v keys", p.keys.Len())
+	}
+}
+
+func TestPolicyUpdate(t *testing.T) {
+	p := newFIFOPolicy()
+	gc := New(10).Policy(func() Policy { return p }).Build()
+
+	gc.Set(0, 0)
+	gc.Set(0, 1)
+	if p.accessed[0] != 1 {
+		t.Fatalf("an update should count as an access, got %v", p.accessed[0])
+	}
+	if p.keys.Len() != 1 {
+		t.Fatalf("policy tracks %v keys, expected 1", p.keys.Len())
+	}
+}
+
+func TestPolicyBuiltTwice(t *testing.T) {
+	cb := New(2).Policy(func() Policy { return newFIFOPolicy() })
+	c1, c2 := cb.Build(), cb.Build()
+	for i := 0; i < 4; i++ {
+		c1.Set(i, i)
+		c2.Set(i, i)
+	}
+	for _, gc := range []Cache{c1, c2} {
+		if l := gc.Len(false); l != 2 {
+			t.Errorf("%v keys, expected 2", l)
+		}
+		if !gc.Has(2) || !gc.Has(3) {
+			t.Errorf("unexpected keys %v", gc.Keys(false))
+		}
+	}
+}
+
+func TestUpdateIsNotAnAccess(t *testing.T) {
+	// an update does not count as a hit for LFU
+	lfu := New(2).LFU().Build()
+	for i := 0; i < 3; i++ {
+		lfu.Set("a", i)
+	}
+	lfu.Set("b", 0)
+	lfu.Get("b")
+	lfu.Set("c", 0)
+	if lfu.Has("a") || !lfu.Has("b") {
+		t.Error("a should be evicted instead of b")
+	}
+
+	// nor does it move a key to t2 of ARC
+	arc := New(10).ARC().Build()
+	arc.Set("a", 0)
+	arc.Set("a", 1)
+	if e, _ := arc.GetEntry("a"); e.List != "t1" {
+		t.Errorf("list %q, expected t1", e.List)
+	}
+}
+
+func TestWeigher(t *testing.T) {
+	size := 10
+	for _, tp := range []string{TYPE_LRU, TYPE_LFU, TYPE_ARC, TYPE_2Q, TYPE_LIRS} {
+		t.Run(tp, func(t *testing.T) {
+			gc := New(size).
+				EvictType(tp).
+				Weigher(func(key, value interface{}) int {
+					return value.(int)
+				}).
+				Build()
+
+			for i := 0; i < 5; i++ {
+				gc.Set(i, 3)
+			}
+			// 3 items of weight 3 fit into 10
+			if l := gc.Len(false); l != 3 {
+				t.Fatalf("%v != 3", l)
+			}
+			// an update that outweighs the cache keeps only that item
+			gc.Set(4, 20)
+			if l := gc.Len(false); l != 1 || !gc.Has(4) {
+				t.Fatalf("only 4 should be cached, got %v", gc.Keys(false))
+			}
+		})
+	}
+}
@@ -0,0 +1,168 @@
+package gcache
+
+import (
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"unsafe"
+)
+
+const (
+	// readStripeSize is the number of accesses a stripe holds before it is
+	// replayed. It must be a power of two.
+	readStripeSize = 16
+	// readMaxStripes bounds the number of stripes.
+	readMaxStripes = 64
+)
+
+// readPath lets Get find entries without taking the cache lock.
+// Entries are looked up in a concurrent index, and hits are recorded in
+// striped ring buffers instead of being passed to the policy. The
+// buffers are replayed into the policy by the reader that fills a stripe if
+// it gets the lock without waiting, which needs Go 1.18, and by every write
+// before it changes the policy. A hit is dropped when its stripe is full, which only makes the
+// order of the policy approximate under heavy contention. Writes are not
+// buffered: they take the lock, so that the cache is within its size and
+// the eviction callbacks have run when Set returns.
+type readPath struct {
+	index    sync.Map // key -> *entry
+	stripes  []readStripe
+	stripeID sync.Pool
+	next     uint32
+	draining uint32
+	lock     sync.Locker
+	apply    func(*entry)
+}
+
+// readStripe is a lossy ring buffer with many producers and one consumer.
+type readStripe struct {
+	head uint32 // only written by the consumer
+	tail uint32
+	buf  [readStripeSize]unsafe.Pointer
+}
+
+// setup prepares the buffers. apply replays a hit into the policy and is
+// called with lock held.
+func (p *readPath) setup(lock sync.Locker, apply func(*entry)) {
+	n := 1
+	for n < 4*runtime.GOMAXPROCS(0) && n < readMaxStripes {
+		n <<= 1
+	}
+	p.stripes = make([]readStripe, n)
+	p.stripeID.New = func() interface{} {
+		id := int(atomic.AddUint32(&p.next, 1)) & (n - 1)
+		return &id
+	}
+	p.lock = lock
+	p.apply = apply
+}
+
+// lookup returns the published entry for key.
+func (p *readPath) lookup(key interface{}) (*entry, bool) {
+	e, ok := p.index.Load(key)
+	if !ok {
+		return nil, false
+	}
+	return e.(*entry), true
+}
+
+// publish makes e visible to readers. The cache lock must be held.
+func (p *readPath) publish(e *entry) {
+	p.index.Store(e.key, e)
+}
+
+// unpublish hides key from readers. The cache lock must be held.
+func (p *readPath) unpublish(key interface{}) {
+	p.index.Delete(key)
+}
+
+// unpublishAll hides every key from readers. The cache lock must be held.
+func (p *readPath) unpublishAll() {
+	p.index.Range(func(key, _ interface{}) bool {
+		p.index.Delete(key)
+		return true
+	})
+}
+
+// afterRead records a hit on e, and replays the buffers if its stripe is full
+// and nobody else is replaying them.
+func (p *readPath) afterRead(e *entry) {
+	id := p.stripeID.Get().(*int)
+	full := p.stripes[*id].record(e)
+	p.stripeID.Put(id)
+	if !full || !atomic.CompareAndSwapUint32(&p.draining, 0, 1) {
+		return
+	}
+	if l, ok := p.lock.(tryLocker); ok {
+		// a reader never waits for a writer: if the lock is busy, the next
+		// write replays the buffers
+		if l.TryLock() {
+			p.drainAll()
+			l.Unlock()
+		}
+	} else {
+		// before Go 1.18 the lock cannot be tried, and waiting for it is
+		// better than dropping every hit until the next write
+		p.lock.Lock()
+		p.drainAll()
+		p.lock.Unlock()
+	}
+	atomic.StoreUint32(&p.draining, 0)
+}
+
+// tryLocker is implemented by sync.Mutex and sync.RWMutex since Go 1.18.
+type tryLocker interface {
+	TryLock() bool
+	Unlock()
+}
+
+// flushReads replays the buffered hits so that a write sees them.
+// The cache lock must be held. If a reader is trying to replay them at the
+// same time, they are left to the next write.
+func (p *readPath) flushReads() {
+	if !atomic.CompareAndSwapUint32(&p.draining, 0, 1) {
+		return
+	}
+	p.drainAll()
+	atomic.StoreUint32(&p.draining, 0)
+}
+
+func (p *readPath) drainAll() {
+	for i := range p.stripes {
+		p.stripes[i].drain(p.apply)
+	}
+}
+
+// record adds e to the stripe, dropping it if the stripe is full or
+// another reader won the slot. It returns true if the stripe should be
+// drained.
+func (s *readStripe) record(e *entry) bool {
+	head := atomic.LoadUint32(&s.head)
+	tail := atomic.LoadUint32(&s.tail)
+	size := tail - head
+	if size >= readStripeSize {
+		return true
+	}
+	if !atomic.CompareAndSwapUint32(&s.tail, tail, tail+1) {
+		return false
+	}
+	atomic.StorePointer(&s.buf[tail&(readStripeSize-1)], unsafe.Pointer(e))
+	return size+1 >= readStripeSize
+}
+
+// drain passes the recorded entries to fn in order. It stops at a slot that
+// was claimed but not written yet, which is picked up by the next drain.
+func (s *readStripe) drain(fn func(*entry)) {
+	head := atomic.LoadUint32(&s.head)
+	tail := atomic.LoadUint32(&s.tail)
+	for ; head != tail; head++ {
+		slot := &s.buf[head&(readStripeSize-1)]
+		e := atomic.LoadPointer(slot)
+		if e == nil {
+			break
+		}
+		atomic.StorePointer(slot, nil)
+		fn((*entry)(e))
+	}
+	atomic.StoreUint32(&s.head, head)
+}
@@ -0,0 +1,166 @@
+package gcache
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+)
+
+func TestReadStripe(t *testing.T) {
+	var s readStripe
+	entries := make([]*entry, readStripeSize+1)
+	for i := range entries {
+		entries[i] = &entry{key: i}
+	}
+	for i := 0; i < readStripeSize-1; i++ {
+		if s.record(entries[i]) {
+			t.Fatalf("stripe should not be full after %v entries", i+1)
+		}
+	}
+	if !s.record(entries[readStripeSize-1]) {
+		t.Fatal("stripe should be full")
+	}
+	// a full stripe drops new entries
+	if !s.record(entries[readStripeSize]) {
+		t.Fatal("stripe should still be full")
+	}
+
+	var keys []interface{}
+	s.drain(func(e *entry) {
+		keys = append(keys, e.key)
+	})
+	if len(keys) != readStripeSize {
+		t.Fatalf("%v entries should be drained, but got %v", readStripeSize, len(keys))
+	}
+	for i, key := range keys {
+		if key != i {
+			t.Fatalf("%v != %v", key, i)
+		}
+	}
+	if s.record(entries[0]) {
+		t.Fatal("drained stripe should not be full")
+	}
+}
+
+// plainLocker hides TryLock, like the locks before Go 1.18.
+type plainLocker struct {
+	mu sync.Mutex
+}
+
+func (l *plainLocker) Lock()   { l.mu.Lock() }
+func (l *plainLocker) Unlock() { l.mu.Unlock() }
+
+func TestReadPathWithoutTryLock(t *testing.T) {
+	var p readPath
+	var applied int
+	p.setup(&plainLocker{}, func(e *entry) {
+		applied++
+	})
+	e := &entry{key: 0}
+	// enough hits to fill a stripe, whichever stripes they go to
+	for i := 0; i < len(p.stripes)*readStripeSize && applied == 0; i++ {
+		p.afterRead(e)
+	}
+	if applied == 0 {
+		t.Fatal("the reader should replay the full stripe")
+	}
+}
+
+func TestBufferedReadsOrder(t *testing.T) {
+	for _, tp := range []string{TYPE_LRU, TYPE_LFU, TYPE_ARC} {
+		t.Run(tp, func(t *testing.T) {
+			size := 10
+			gc := buildTestCache(t, tp, size)
+			setItemsByRange(t, gc, 0, size)
+			// more hits than a stripe holds, so that some of them are
+			// replayed by the reader and the rest by the next write
+			for i := 0; i < 3*readStripeSize; i++ {
+				gc.Get(i%(size-1) + 1)
+			}
+			gc.Set(size, size)
+			if gc.Has(0) {
+				t.Fatal("0 should be evicted")
+			}
+			for i := 1; i <= size; i++ {
+				if !gc.Has(i) {
+					t.Fatalf("%v should be kept", i)
+				}
+			}
+		})
+	}
+}
+
+func TestBufferedReadsStaleEntry(t *testing.T) {
+	for _, tp := range []string{TYPE_LRU, TYPE_LFU, TYPE_ARC} {
+		t.Run(tp, func(t *testing.T) {
+			gc := buildTestCache(t, tp, 2)
+			gc.Set(0, 0)
+			gc.Get(0)
+			// the buffered hit refers to an item that is gone
+			gc.Remove(0)
+			gc.Set(1, 1)
+			gc.Set(0, 0)
+			if l := gc.Len(false); l != 2 {
+				t.Fatalf("%v != %v", l, 2)
+			}
+			if v, err := gc.Get(0); err != nil || v != 0 {
+				t.Fatalf("0 should be cached: %v, %v", v, err)
+			}
+		})
+	}
+}
+
+func TestLRUConcurrentGet(t *testing.T) {
+	testConcurrentGet(t, TYPE_LRU)
+}
+
+func TestLFUConcurrentGet(t *testing.T) {
+	testConcurrentGet(t, TYPE_LFU)
+}
+
+func TestARCConcurrentGet(t *testing.T) {
+	testConcurrentGet(t, TYPE_ARC)
+}
+
+func BenchmarkParallelGet(b *testing.B) {
+	size := 1000
+	for _, tp := range []string{TYPE_LRU, TYPE_LFU, TYPE_ARC, TYPE_2Q} {
+		b.Run(tp, func(b *testing.B) {
+			gc := New(size).EvictType(tp).Build()
+			for i := 0; i < size; i++ {
+				gc.Set(i, i)
+			}
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				i := 0
+				for pb.Next() {
+					if _, err := gc.Get(i % size); err != nil {
+						b.Fatal(err)
+					}
+					i++
+				}
+			})
+		})
+	}
+}
+
+func BenchmarkParallelGetSet(b *testing.B) {
+	size := 1000
+	for _, tp := range []string{TYPE_LRU, TYPE_LFU, TYPE_ARC, TYPE_2Q} {
+		b.Run(tp, func(b *testing.B) {
+			gc := New(size).EvictType(tp).Build()
+			b.RunParallel(func(pb *testing.PB) {
+				i := 0
+				for pb.Next() {
+					key := fmt.Sprint(i % (2 * size))
+					if i%10 == 0 {
+						gc.Set(key, i)
+					} else {
+						gc.Get(key)
+					}
+					i++
+				}
+			})
+		})
+	}
+}
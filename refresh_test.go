@@ -0,0 +1,134 @@
+package gcache
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestEarlyRefresh(t *testing.T) {
+	clock := NewFakeClock()
+	var loads int32
+	ttl := time.Minute
+	gc := New(10).
+		LRU().
+		Clock(clock).
+		EarlyRefresh(1).
+		LoaderExpireFunc(func(key interface{}) (interface{}, *time.Duration, error) {
+			// every load takes a second
+			clock.Advance(time.Second)
+			return atomic.AddInt32(&loads, 1), &ttl, nil
+		}).
+		Build()
+
+	if _, err := gc.Get("key"); err != nil {
+		t.Fatal(err)
+	}
+	// far from the deadline, a refresh is practically impossible
+	for i := 0; i < 1000; i++ {
+		if _, err := gc.Get("key"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := atomic.LoadInt32(&loads); n != 1 {
+		t.Fatalf("%v loads, expected 1", n)
+	}
+
+	// shortly before the deadline, reads refresh the entry in the background
+	clock.Advance(ttl - 100*time.Millisecond)
+	for i := 0; i < 100 && atomic.LoadInt32(&loads) == 1; i++ {
+		if _, err := gc.Get("key"); err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(time.Millisecond)
+	}
+	for i := 0; i < 100; i++ {
+		if v, _ := gc.Get("key"); v == int32(2) {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if v, _ := gc.Get("key"); v != int32(2) {
+		t.Fatalf("%v != 2, the entry was not refreshed", v)
+	}
+	if n := gc.MissCount(); n != 1 {
+		t.Errorf("%v misses, expected 1", n)
+	}
+}
+
+func TestEarlyRefreshWithoutLoadTime(t *testing.T) {
+	clock := NewFakeClock()
+	var loads int32
+	ttl := time.Minute
+	gc := New(10).
+		LRU().
+		Clock(clock).
+		EarlyRefresh(1).
+		LoaderExpireFunc(func(key interface{}) (interface{}, *time.Duration, error) {
+			return atomic.AddInt32(&loads, 1), &ttl, nil
+		}).
+		Build()
+	gc.Get("key")
+	clock.Advance(ttl)
+	for i := 0; i < 100; i++ {
+		gc.Get("key")
+	}
+	if n := atomic.LoadInt32(&loads); n != 1 {
+		t.Errorf("%v loads, expected 1", n)
+	}
+}
+
+func TestEarlyRefreshOncePerEntry(t *testing.T) {
+	clock := NewFakeClock()
+	var loads int32
+	release := make(chan error)
+	ttl := time.Minute
+	gc := New(10).
+		LRU().
+		Clock(clock).
+		EarlyRefresh(1).
+		LoaderExpireFunc(func(key interface{}) (interface{}, *time.Duration, error) {
+			n := atomic.AddInt32(&loads, 1)
+			if n == 1 {
+				clock.Advance(time.Second)
+				return n, &ttl, nil
+			}
+			return n, &ttl, <-release
+		}).
+		Build().(*LRUCache)
+
+	if _, err := gc.Get("key"); err != nil {
+		t.Fatal(err)
+	}
+	e := gc.items["key"]
+	clock.Advance(ttl - 100*time.Millisecond)
+	for i := 0; i < 100 && atomic.LoadInt32(&loads) == 1; i++ {
+		gc.Get("key")
+		time.Sleep(time.Millisecond)
+	}
+	// while the refresh runs, further hits do not start another one
+	for i := 0; i < 1000; i++ {
+		gc.Get("key")
+	}
+	if n := atomic.LoadInt32(&loads); n != 2 {
+		t.Fatalf("%v loads, expected 2", n)
+	}
+	if atomic.LoadUint32(&e.refreshing) != 1 {
+		t.Fatal("the entry is not marked as refreshing")
+	}
+
+	// after a failed refresh, the next hit tries again
+	release <- errors.New("failed")
+	for i := 0; i < 100 && atomic.LoadUint32(&e.refreshing) == 1; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	for i := 0; i < 100 && atomic.LoadInt32(&loads) == 2; i++ {
+		gc.Get("key")
+		time.Sleep(time.Millisecond)
+	}
+	if n := atomic.LoadInt32(&loads); n != 3 {
+		t.Fatalf("%v loads, expected 3", n)
+	}
+	release <- nil
+}
@@ -0,0 +1,165 @@
+package gcache
+
+import (
+	"container/list"
+)
+
+const (
+	// s3fifoSmallRatio is the share of the capacity given to the small queue.
+	s3fifoSmallRatio = 0.1
+	// s3fifoMaxFreq caps the access counter of an item.
+	s3fifoMaxFreq = 3
+)
+
+// S3FIFOCache implements the S3-FIFO algorithm.
+// New items enter a small FIFO queue holding a tenth of the capacity. Items
+// that were accessed while in the small queue are moved to the main FIFO
+// queue when they reach its tail; the others are evicted and their keys are
+// remembered in a ghost queue, so that they go straight to the main queue if
+// they are inserted again. The main queue reinserts items that were accessed
+// since they were last at its tail. A hit only bumps a counter.
+type S3FIFOCache struct {
+	baseCache
+}
+
+var _ Cache = (*S3FIFOCache)(nil)
+
+func newS3FIFOCache(cb *CacheBuilder) *S3FIFOCache {
+	c := &S3FIFOCache{}
+	buildCache(&c.baseCache, cb, newS3FIFOPolicy())
+	return c
+}
+
+type s3fifoPolicy struct {
+	items map[interface{}]*s3fifoItem
+	small *list.List // newest key at the front
+	main  *list.List // newest key at the front
+	ghost *arcList   // keys recently evicted from small
+
+	smallSize int
+	ghostSize int
+}
+
+type s3fifoItem struct {
+	key     interface{}
+	freq    accessMark
+	main    bool // true if the key lives in the main queue
+	element *list.Element
+}
+
+func newS3FIFOPolicy() *s3fifoPolicy {
+	p := &s3fifoPolicy{}
+	p.reset()
+	return p
+}
+
+func (p *s3fifoPolicy) reset() {
+	p.items = make(map[interface{}]*s3fifoItem)
+	p.small = list.New()
+	p.main = list.New()
+	p.ghost = newARCList()
+}
+
+// SetSize derives the small and ghost queue limits from the cache size.
+func (p *s3fifoPolicy) SetSize(size int) {
+	p.smallSize = maxInt(1, int(float64(size)*s3fifoSmallRatio))
+	p.ghostSize = maxInt(1, size-p.smallSize)
+	p.trimGhosts()
+}
+
+func (p *s3fifoPolicy) OnInsert(key interface{}) {
+	item := &s3fifoItem{key: key, freq: accessMark{max: s3fifoMaxFreq}}
+	if elt := p.ghost.Lookup(key); elt != nil {
+		p.ghost.Remove(key, elt)
+		item.main = true
+		item.element = p.main.PushFront(item)
+	} else {
+		item.element = p.small.PushFront(item)
+	}
+	p.items[key] = item
+}
+
+// OnAccess bumps the counter of key, saturating at s3fifoMaxFreq.
+func (p *s3fifoPolicy) OnAccess(key interface{}) {
+	if item, ok := p.items[key]; ok {
+		item.freq.hit()
+	}
+}
+
+func (p *s3fifoPolicy) mark(key interface{}) *accessMark {
+	if item, ok := p.items[key]; ok {
+		return &item.freq
+	}
+	return nil
+}
+
+func (p *s3fifoPolicy) OnRemove(key interface{}) {
+	if item, ok := p.items[key]; ok {
+		p.removeItem(item)
+	}
+}
+
+// Victim evicts from the small queue while it holds at least its share of
+// the capacity, and from the main queue otherwise.
+func (p *s3fifoPolicy) Victim() (interface{}, bool) {
+	for {
+		switch {
+		case p.small.Len() > 0 && (p.small.Len() >= p.smallSize || p.main.Len() == 0):
+			if item := p.evictSmall(); item != nil {
+				return item.key, true
+			}
+		case p.main.Len() > 0:
+			return p.evictMain().key, true
+		default:
+			return nil, false
+		}
+	}
+}
+
+// evictSmall looks at the tail of the small queue. An item accessed since it
+// was inserted is moved to the main queue and nil is returned. Otherwise
+// the item is evicted, its key is remembered in the ghost queue and the item
+// is returned.
+func (p *s3fifoPolicy) evictSmall() *s3fifoItem {
+	item := p.small.Back().Value.(*s3fifoItem)
+	p.small.Remove(item.element)
+	if item.freq.count() > 0 {
+		item.freq.clear()
+		item.main = true
+		item.element = p.main.PushFront(item)
+		return nil
+	}
+	p.ghost.PushFront(item.key)
+	p.trimGhosts()
+	delete(p.items, item.key)
+	return item
+}
+
+// evictMain evicts the first item from the tail of the main queue that was
+// not accessed since it was last there, reinserting the others.
+func (p *s3fifoPolicy) evictMain() *s3fifoItem {
+	for {
+		item := p.main.Back().Value.(*s3fifoItem)
+		if item.freq.decrement() {
+			p.main.MoveToFront(item.element)
+			continue
+		}
+		p.removeItem(item)
+		return item
+	}
+}
+
+func (p *s3fifoPolicy) removeItem(item *s3fifoItem) {
+	if item.main {
+		p.main.Remove(item.element)
+	} else {
+		p.small.Remove(item.element)
+	}
+	delete(p.items, item.key)
+}
+
+func (p *s3fifoPolicy) trimGhosts() {
+	for p.ghost.Len() > p.ghostSize {
+		p.ghost.RemoveTail()
+	}
+}
@@ -0,0 +1,173 @@
+package gcache
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestS3FIFOGet(t *testing.T) {
+	size := 1000
+	gc := buildTestCache(t, TYPE_S3FIFO, size)
+	testSetCache(t, gc, size)
+	testGetCache(t, gc, size)
+}
+
+func TestLoadingS3FIFOGet(t *testing.T) {
+	size := 1000
+	numbers := 1000
+	testGetCache(t, buildTestLoadingCache(t, TYPE_S3FIFO, size, loader), numbers)
+}
+
+func TestS3FIFOLength(t *testing.T) {
+	clock := NewFakeClock()
+	gc := buildTestLoadingCacheWithClock(t, TYPE_S3FIFO, 2, time.Millisecond, clock)
+	gc.Get("test1")
+	gc.Get("test2")
+	gc.Get("test3")
+	length := gc.Len(true)
+	expectedLength := 2
+	if length != expectedLength {
+		t.Errorf("Expected length is %v, not %v", expectedLength, length)
+	}
+	clock.Advance(2 * time.Millisecond)
+	gc.Get("test4")
+	length = gc.Len(true)
+	expectedLength = 1
+	if length != expectedLength {
+		t.Errorf("Expected length is %v, not %v", expectedLength, length)
+	}
+}
+
+func TestS3FIFOEvictItem(t *testing.T) {
+	cacheSize := 10
+	numbers := cacheSize + 1
+	gc := buildTestLoadingCache(t, TYPE_S3FIFO, cacheSize, loader)
+
+	for i := 0; i < numbers; i++ {
+		_, err := gc.Get(fmt.Sprintf("Key-%d", i))
+		if err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+	}
+	if l := gc.Len(false); l != cacheSize {
+		t.Errorf("Expected length is %v, not %v", cacheSize, l)
+	}
+}
+
+func TestS3FIFOPurgeCache(t *testing.T) {
+	cacheSize := 10
+	purgeCount := 0
+	gc := New(cacheSize).
+		S3FIFO().
+		LoaderFunc(loader).
+		PurgeVisitorFunc(func(k, v interface{}) {
+			purgeCount++
+		}).
+		Build()
+
+	for i := 0; i < cacheSize; i++ {
+		_, err := gc.Get(fmt.Sprintf("Key-%d", i))
+		if err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+	}
+
+	gc.Purge()
+
+	if purgeCount != cacheSize {
+		t.Errorf("failed to purge everything")
+	}
+}
+
+func TestS3FIFOGetIFPresent(t *testing.T) {
+	testGetIFPresent(t, TYPE_S3FIFO)
+}
+
+func TestS3FIFOHas(t *testing.T) {
+	gc := buildTestLoadingCacheWithExpiration(t, TYPE_S3FIFO, 2, 10*time.Millisecond)
+
+	for i := 0; i < 10; i++ {
+		t.Run(fmt.Sprint(i), func(t *testing.T) {
+			gc.Get("test1")
+			gc.Get("test2")
+
+			if gc.Has("test0") {
+				t.Fatal("should not have test0")
+			}
+			if !gc.Has("test1") {
+				t.Fatal("should have test1")
+			}
+			if !gc.Has("test2") {
+				t.Fatal("should have test2")
+			}
+
+			time.Sleep(20 * time.Millisecond)
+
+			if gc.Has("test0") {
+				t.Fatal("should not have test0")
+			}
+			if gc.Has("test1") {
+				t.Fatal("should not have test1")
+			}
+			if gc.Has("test2") {
+				t.Fatal("should not have test2")
+			}
+		})
+	}
+}
+
+func TestS3FIFOOneHitWonders(t *testing.T) {
+	size := 20
+	gc := buildTestCache(t, TYPE_S3FIFO, size).(*S3FIFOCache)
+	p := gc.policy.(*s3fifoPolicy)
+
+	setItemsByRange(t, gc, 0, size)
+	for i := 0; i < size/2; i++ {
+		gc.Get(i)
+	}
+	// items inserted once and never read leave through the small queue,
+	// while the items read at least once are moved to the main queue
+	setItemsByRange(t, gc, 100, 100+size)
+	for i := 0; i < size/2; i++ {
+		if !gc.Has(i) {
+			t.Fatalf("%v should be cached", i)
+		}
+		if !p.items[i].main {
+			t.Fatalf("%v should be in the main queue", i)
+		}
+	}
+	for i := size / 2; i < size; i++ {
+		if gc.Has(i) {
+			t.Fatalf("%v should be evicted", i)
+		}
+	}
+	if l := gc.Len(false); l != size {
+		t.Fatalf("%v != %v", l, size)
+	}
+}
+
+func TestS3FIFOGhostPromotion(t *testing.T) {
+	size := 20
+	gc := buildTestCache(t, TYPE_S3FIFO, size).(*S3FIFOCache)
+	p := gc.policy.(*s3fifoPolicy)
+
+	setItemsByRange(t, gc, 0, size+1)
+	if gc.Has(0) {
+		t.Fatal("0 should be evicted")
+	}
+	if !p.ghost.Has(0) {
+		t.Fatal("0 should be remembered in the ghost queue")
+	}
+	gc.Set(0, 0)
+	if !p.items[0].main {
+		t.Fatal("0 should be inserted into the main queue")
+	}
+	if p.ghost.Has(0) {
+		t.Fatal("0 should be removed from the ghost queue")
+	}
+}
+
+func TestS3FIFOConcurrentGet(t *testing.T) {
+	testConcurrentGet(t, TYPE_S3FIFO)
+}
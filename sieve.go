@@ -0,0 +1,97 @@
+package gcache
+
+import (
+	"container/list"
+)
+
+// SieveCache implements the SIEVE algorithm.
+// Items are kept in insertion order and a hit only marks the item as
+// visited. On eviction a hand sweeps from the oldest item towards the
+// newest, clearing visited marks, and evicts the first item that was not
+// visited since the hand last passed it.
+type SieveCache struct {
+	baseCache
+}
+
+var _ Cache = (*SieveCache)(nil)
+
+func newSieveCache(cb *CacheBuilder) *SieveCache {
+	c := &SieveCache{}
+	buildCache(&c.baseCache, cb, newSievePolicy())
+	return c
+}
+
+type sievePolicy struct {
+	items map[interface{}]*list.Element
+	queue *list.List // newest key at the front
+	hand  *list.Element
+}
+
+type sieveItem struct {
+	key     interface{}
+	visited accessMark
+}
+
+func newSievePolicy() *sievePolicy {
+	p := &sievePolicy{}
+	p.reset()
+	return p
+}
+
+func (p *sievePolicy) reset() {
+	p.queue = list.New()
+	p.items = make(map[interface{}]*list.Element)
+	p.hand = nil
+}
+
+func (p *sievePolicy) OnInsert(key interface{}) {
+	p.items[key] = p.queue.PushFront(&sieveItem{key: key, visited: accessMark{max: 1}})
+}
+
+func (p *sievePolicy) OnAccess(key interface{}) {
+	if elt, ok := p.items[key]; ok {
+		elt.Value.(*sieveItem).visited.hit()
+	}
+}
+
+func (p *sievePolicy) mark(key interface{}) *accessMark {
+	if elt, ok := p.items[key]; ok {
+		return &elt.Value.(*sieveItem).visited
+	}
+	return nil
+}
+
+func (p *sievePolicy) OnRemove(key interface{}) {
+	if elt, ok := p.items[key]; ok {
+		p.removeElement(elt)
+	}
+}
+
+// Victim moves the hand from the oldest key towards the newest one and
+// returns the first key that has not been visited since the hand last passed.
+func (p *sievePolicy) Victim() (interface{}, bool) {
+	hand := p.hand
+	if hand == nil {
+		hand = p.queue.Back()
+	}
+	if hand == nil {
+		return nil, false
+	}
+	for hand.Value.(*sieveItem).visited.count() > 0 {
+		hand.Value.(*sieveItem).visited.clear()
+		if hand = hand.Prev(); hand == nil {
+			hand = p.queue.Back()
+		}
+	}
+	p.hand = hand
+	p.removeElement(hand)
+	return hand.Value.(*sieveItem).key, true
+}
+
+func (p *sievePolicy) removeElement(e *list.Element) {
+	if p.hand == e {
+		p.hand = e.Prev()
+	}
+	p.queue.Remove(e)
+	delete(p.items, e.Value.(*sieveItem).key)
+}
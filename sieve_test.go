@@ -0,0 +1,164 @@
+package gcache
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestSieveGet(t *testing.T) {
+	size := 1000
+	gc := buildTestCache(t, TYPE_SIEVE, size)
+	testSetCache(t, gc, size)
+	testGetCache(t, gc, size)
+}
+
+func TestLoadingSieveGet(t *testing.T) {
+	size := 1000
+	numbers := 1000
+	testGetCache(t, buildTestLoadingCache(t, TYPE_SIEVE, size, loader), numbers)
+}
+
+func TestSieveLength(t *testing.T) {
+	clock := NewFakeClock()
+	gc := buildTestLoadingCacheWithClock(t, TYPE_SIEVE, 2, time.Millisecond, clock)
+	gc.Get("test1")
+	gc.Get("test2")
+	gc.Get("test3")
+	length := gc.Len(true)
+	expectedLength := 2
+	if length != expectedLength {
+		t.Errorf("Expected length is %v, not %v", expectedLength, length)
+	}
+	clock.Advance(2 * time.Millisecond)
+	gc.Get("test4")
+	length = gc.Len(true)
+	expectedLength = 1
+	if length != expectedLength {
+		t.Errorf("Expected length is %v, not %v", expectedLength, length)
+	}
+}
+
+func TestSieveEvictItem(t *testing.T) {
+	cacheSize := 10
+	numbers := cacheSize + 1
+	gc := buildTestLoadingCache(t, TYPE_SIEVE, cacheSize, loader)
+
+	for i := 0; i < numbers; i++ {
+		_, err := gc.Get(fmt.Sprintf("Key-%d", i))
+		if err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+	}
+	if l := gc.Len(false); l != cacheSize {
+		t.Errorf("Expected length is %v, not %v", cacheSize, l)
+	}
+}
+
+func TestSievePurgeCache(t *testing.T) {
+	cacheSize := 10
+	purgeCount := 0
+	gc := New(cacheSize).
+		Sieve().
+		LoaderFunc(loader).
+		PurgeVisitorFunc(func(k, v interface{}) {
+			purgeCount++
+		}).
+		Build()
+
+	for i := 0; i < cacheSize; i++ {
+		_, err := gc.Get(fmt.Sprintf("Key-%d", i))
+		if err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+	}
+
+	gc.Purge()
+
+	if purgeCount != cacheSize {
+		t.Errorf("failed to purge everything")
+	}
+}
+
+func TestSieveGetIFPresent(t *testing.T) {
+	testGetIFPresent(t, TYPE_SIEVE)
+}
+
+func TestSieveHas(t *testing.T) {
+	gc := buildTestLoadingCacheWithExpiration(t, TYPE_SIEVE, 2, 10*time.Millisecond)
+
+	for i := 0; i < 10; i++ {
+		t.Run(fmt.Sprint(i), func(t *testing.T) {
+			gc.Get("test1")
+			gc.Get("test2")
+
+			if gc.Has("test0") {
+				t.Fatal("should not have test0")
+			}
+			if !gc.Has("test1") {
+				t.Fatal("should have test1")
+			}
+			if !gc.Has("test2") {
+				t.Fatal("should have test2")
+			}
+
+			time.Sleep(20 * time.Millisecond)
+
+			if gc.Has("test0") {
+				t.Fatal("should not have test0")
+			}
+			if gc.Has("test1") {
+				t.Fatal("should not have test1")
+			}
+			if gc.Has("test2") {
+				t.Fatal("should not have test2")
+			}
+		})
+	}
+}
+
+func TestSieveVisitedSurvives(t *testing.T) {
+	size := 4
+	gc := buildTestCache(t, TYPE_SIEVE, size)
+
+	setItemsByRange(t, gc, 0, size)
+	gc.Get(0)
+	gc.Get(2)
+	// the hand skips the visited items 0 and 2 and evicts 1, then 3
+	setItemsByRange(t, gc, size, size+2)
+	for _, key := range []int{0, 2, 4, 5} {
+		if !gc.Has(key) {
+			t.Fatalf("%v should be cached", key)
+		}
+	}
+	for _, key := range []int{1, 3} {
+		if gc.Has(key) {
+			t.Fatalf("%v should be evicted", key)
+		}
+	}
+
+	// the hand keeps its position instead of restarting from the tail,
+	// so the newer item 4 goes before 0
+	setItemsByRange(t, gc, size+2, size+3)
+	if gc.Has(4) {
+		t.Fatal("4 should be evicted")
+	}
+	if !gc.Has(0) {
+		t.Fatal("0 should be cached")
+	}
+}
+
+func TestSieveConcurrentGet(t *testing.T) {
+	testConcurrentGet(t, TYPE_SIEVE)
+}
+
+func TestSieveHitIsNotBuffered(t *testing.T) {
+	gc := buildTestCache(t, TYPE_SIEVE, 4).(*SieveCache)
+	gc.Set(0, 0)
+	gc.Get(0)
+	// the hit is visible to the policy without replaying the read buffers
+	p := gc.policy.(*sievePolicy)
+	if n := p.items[0].Value.(*sieveItem).visited.count(); n != 1 {
+		t.Errorf("%v marks, expected 1", n)
+	}
+}
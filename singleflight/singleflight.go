@@ -0,0 +1,158 @@
+/*
+Copyright 2012 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package singleflight provides a duplicate function call suppression
+// mechanism.
+package singleflight
+
+import (
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"sync"
+)
+
+// errGoexit is the error of a call whose function called runtime.Goexit.
+var errGoexit = errors.New("singleflight: function called runtime.Goexit")
+
+// PanicError is the panic of a function called through a Group, with the
+// stack of the goroutine that panicked.
+type PanicError struct {
+	Value interface{}
+	Stack []byte
+}
+
+func (p *PanicError) Error() string {
+	return fmt.Sprintf("singleflight: %v\n\n%s", p.Value, p.Stack)
+}
+
+// call is an in-flight or completed Do call
+type call struct {
+	wg  sync.WaitGroup
+	val interface{}
+	err error
+
+	panicErr *PanicError
+	dups     int
+	chans    []chan<- Result
+}
+
+// Group represents a class of work and forms a namespace in which
+// units of work can be executed with duplicate suppression.
+// The zero value is ready to use.
+type Group struct {
+	mu sync.Mutex            // protects m
+	m  map[interface{}]*call // lazily initialized
+}
+
+// Result holds the results of Do, so they can be passed on a channel.
+type Result struct {
+	Val    interface{}
+	Err    error
+	Shared bool
+}
+
+// Do executes and returns the results of the given function, making
+// sure that only one execution is in-flight for a given key at a
+// time. If a duplicate comes in, the duplicate caller waits for the
+// original to complete and receives the same results. shared reports
+// whether the results were given to multiple callers. If the function
+// panics, every caller panics with a *PanicError.
+func (g *Group) Do(key interface{}, fn func() (interface{}, error)) (v interface{}, err error, shared bool) {
+	g.mu.Lock()
+	if g.m == nil {
+		g.m = make(map[interface{}]*call)
+	}
+	if c, ok := g.m[key]; ok {
+		c.dups++
+		g.mu.Unlock()
+		c.wg.Wait()
+		if c.panicErr != nil {
+			panic(c.panicErr)
+		}
+		return c.val, c.err, true
+	}
+	c := new(call)
+	c.wg.Add(1)
+	g.m[key] = c
+	g.mu.Unlock()
+
+	g.doCall(c, key, fn)
+	if c.panicErr != nil {
+		panic(c.panicErr)
+	}
+	return c.val, c.err, c.dups > 0
+}
+
+// DoChan is like Do but returns a channel that will receive the results
+// when they are ready, without waiting for them. If the function panics,
+// the *PanicError is sent as the error instead.
+func (g *Group) DoChan(key interface{}, fn func() (interface{}, error)) <-chan Result {
+	ch := make(chan Result, 1)
+	g.mu.Lock()
+	if g.m == nil {
+		g.m = make(map[interface{}]*call)
+	}
+	if c, ok := g.m[key]; ok {
+		c.dups++
+		c.chans = append(c.chans, ch)
+		g.mu.Unlock()
+		return ch
+	}
+	c := &call{chans: []chan<- Result{ch}}
+	c.wg.Add(1)
+	g.m[key] = c
+	g.mu.Unlock()
+
+	go g.doCall(c, key, fn)
+	return ch
+}
+
+// doCall calls fn and hands its results to the waiting callers.
+func (g *Group) doCall(c *call, key interface{}, fn func() (interface{}, error)) {
+	returned := false
+	defer func() {
+		if !returned {
+			// recover returns nil if fn called runtime.Goexit
+			if r := recover(); r != nil {
+				c.panicErr = &PanicError{Value: r, Stack: debug.Stack()}
+				c.err = c.panicErr
+			} else {
+				c.err = errGoexit
+			}
+		}
+
+		g.mu.Lock()
+		defer g.mu.Unlock()
+		c.wg.Done()
+		if g.m[key] == c {
+			delete(g.m, key)
+		}
+		for _, ch := range c.chans {
+			ch <- Result{Val: c.val, Err: c.err, Shared: c.dups > 0}
+		}
+	}()
+	c.val, c.err = fn()
+	returned = true
+}
+
+// Forget tells the Group to forget about key. Future calls for it will call
+// the function rather than wait for an earlier call to complete.
+func (g *Group) Forget(key interface{}) {
+	g.mu.Lock()
+	delete(g.m, key)
+	g.mu.Unlock()
+}
@@ -0,0 +1,161 @@
+/*
+Copyright 2012 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package singleflight
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestDo(t *testing.T) {
+	var g Group
+	v, err, _ := g.Do("key", func() (interface{}, error) {
+		return "bar", nil
+	})
+	if got, want := fmt.Sprintf("%v (%T)", v, v), "bar (string)"; got != want {
+		t.Errorf("Do = %v; want %v", got, want)
+	}
+	if err != nil {
+		t.Errorf("Do error = %v", err)
+	}
+}
+
+func TestDoErr(t *testing.T) {
+	var g Group
+	someErr := errors.New("Some error")
+	v, err, _ := g.Do("key", func() (interface{}, error) {
+		return nil, someErr
+	})
+	if err != someErr {
+		t.Errorf("Do error = %v; want someErr", err)
+	}
+	if v != nil {
+		t.Errorf("unexpected non-nil value %#v", v)
+	}
+}
+
+func TestDoDupSuppress(t *testing.T) {
+	var g Group
+	c := make(chan string)
+	var calls int32
+	fn := func() (interface{}, error) {
+		atomic.AddInt32(&calls, 1)
+		return <-c, nil
+	}
+
+	const n = 10
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			v, err, _ := g.Do("key", fn)
+			if err != nil {
+				t.Errorf("Do error: %v", err)
+			}
+			if v.(string) != "bar" {
+				t.Errorf("got %q; want %q", v, "bar")
+			}
+			wg.Done()
+		}()
+	}
+	time.Sleep(100 * time.Millisecond) // let goroutines above block
+	c <- "bar"
+	wg.Wait()
+	if got := atomic.LoadInt32(&calls); got != 1 {
+		t.Errorf("number of calls = %d; want 1", got)
+	}
+}
+
+func TestDoShared(t *testing.T) {
+	var g Group
+	c := make(chan string)
+	fn := func() (interface{}, error) {
+		return <-c, nil
+	}
+	first := g.DoChan("key", fn)
+	second := g.DoChan("key", fn)
+	c <- "bar"
+	for _, ch := range []<-chan Result{first, second} {
+		r := <-ch
+		if r.Val != "bar" || r.Err != nil || !r.Shared {
+			t.Errorf("unexpected result %+v", r)
+		}
+	}
+	v, err, shared := g.Do("key", func() (interface{}, error) {
+		return "baz", nil
+	})
+	if v != "baz" || err != nil || shared {
+		t.Errorf("Do = %v, %v, %v", v, err, shared)
+	}
+}
+
+func TestForget(t *testing.T) {
+	var g Group
+	release := make(chan struct{})
+	started := make(chan struct{})
+	first := g.DoChan("key", func() (interface{}, error) {
+		close(started)
+		<-release
+		return 1, nil
+	})
+	<-started
+	g.Forget("key")
+	v, _, shared := g.Do("key", func() (interface{}, error) {
+		return 2, nil
+	})
+	if v != 2 || shared {
+		t.Errorf("Do = %v, %v; want 2, false", v, shared)
+	}
+	close(release)
+	if r := <-first; r.Val != 1 {
+		t.Errorf("DoChan = %v; want 1", r.Val)
+	}
+}
+
+func TestDoPanic(t *testing.T) {
+	var g Group
+	func() {
+		defer func() {
+			p, ok := recover().(*PanicError)
+			if !ok || p.Value != "boom" {
+				t.Fatalf("recovered %#v", p)
+			}
+			if !strings.Contains(string(p.Stack), "TestDoPanic") {
+				t.Errorf("the stack does not show the panic:\n%s", p.Stack)
+			}
+		}()
+		g.Do("key", func() (interface{}, error) {
+			panic("boom")
+		})
+	}()
+
+	r := <-g.DoChan("key", func() (interface{}, error) {
+		panic("boom")
+	})
+	if p, ok := r.Err.(*PanicError); !ok || p.Value != "boom" {
+		t.Errorf("DoChan error = %v", r.Err)
+	}
+	// the key is usable again after a panic
+	if v, err, _ := g.Do("key", func() (interface{}, error) { return "ok", nil }); v != "ok" || err != nil {
+		t.Errorf("Do = %v, %v", v, err)
+	}
+}
@@ -0,0 +1,46 @@
+//go:build go1.21
+// +build go1.21
+
+package singleflight
+
+// TypedGroup is a Group for keys of type K and values of type V.
+// The zero value is ready to use.
+type TypedGroup[K comparable, V any] struct {
+	g Group
+}
+
+// TypedResult holds the results of TypedGroup.Do, so they can be passed on
+// a channel.
+type TypedResult[V any] struct {
+	Val    V
+	Err    error
+	Shared bool
+}
+
+// Do is Group.Do for typed keys and values.
+func (g *TypedGroup[K, V]) Do(key K, fn func() (V, error)) (v V, err error, shared bool) {
+	val, err, shared := g.g.Do(key, func() (interface{}, error) {
+		return fn()
+	})
+	v, _ = val.(V)
+	return v, err, shared
+}
+
+// DoChan is Group.DoChan for typed keys and values.
+func (g *TypedGroup[K, V]) DoChan(key K, fn func() (V, error)) <-chan TypedResult[V] {
+	ch := make(chan TypedResult[V], 1)
+	rc := g.g.DoChan(key, func() (interface{}, error) {
+		return fn()
+	})
+	go func() {
+		r := <-rc
+		v, _ := r.Val.(V)
+		ch <- TypedResult[V]{Val: v, Err: r.Err, Shared: r.Shared}
+	}()
+	return ch
+}
+
+// Forget is Group.Forget for typed keys.
+func (g *TypedGroup[K, V]) Forget(key K) {
+	g.g.Forget(key)
+}
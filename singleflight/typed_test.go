@@ -0,0 +1,27 @@
+//go:build go1.21
+// +build go1.21
+
+package singleflight
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestTypedGroup(t *testing.T) {
+	var g TypedGroup[string, int]
+	v, err, shared := g.Do("key", func() (int, error) {
+		return 42, nil
+	})
+	if v != 42 || err != nil || shared {
+		t.Errorf("Do = %v, %v, %v", v, err, shared)
+	}
+
+	someErr := errors.New("some error")
+	r := <-g.DoChan("key", func() (int, error) {
+		return 0, someErr
+	})
+	if r.Val != 0 || r.Err != someErr {
+		t.Errorf("DoChan = %+v", r)
+	}
+}
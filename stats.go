@@ -9,12 +9,30 @@ type statsAccessor interface {
 	MissCount() uint64
 	LookupCount() uint64
 	HitRate() float64
+	ShrinkCount() uint64
+	GrowCount() uint64
+	Capacity() int
+	ActivePolicy() string
+	PolicySwitchCount() uint64
+	LoaderRetryCount() uint64
+	CircuitState() CircuitState
+	CircuitOpenCount() uint64
 }
 
 // statistics
 type stats struct {
-	hitCount  uint64
-	missCount uint64
+	hitCount    uint64
+	missCount   uint64
+	shrinkCount uint64
+	growCount   uint64
+	capacity    int64
+
+	policySwitchCount uint64
+	activePolicy      atomic.Value // string
+
+	loaderRetryCount uint64
+	circuitOpenCount uint64
+	circuitState     int32
 }
 
 // increment hit count
@@ -51,3 +69,82 @@ func (st *stats) HitRate() float64 {
 	}
 	return float64(hc) / float64(total)
 }
+
+// increment count of capacity reductions made under memory pressure
+func (st *stats) IncrShrinkCount() uint64 {
+	return atomic.AddUint64(&st.shrinkCount, 1)
+}
+
+// increment count of capacity increases made after memory pressure is gone
+func (st *stats) IncrGrowCount() uint64 {
+	return atomic.AddUint64(&st.growCount, 1)
+}
+
+// ShrinkCount returns how many times the memory controller shrank the cache
+func (st *stats) ShrinkCount() uint64 {
+	return atomic.LoadUint64(&st.shrinkCount)
+}
+
+// GrowCount returns how many times the memory controller grew the cache back
+func (st *stats) GrowCount() uint64 {
+	return atomic.LoadUint64(&st.growCount)
+}
+
+// Capacity returns the number of entries the cache currently allows
+func (st *stats) Capacity() int {
+	return int(atomic.LoadInt64(&st.capacity))
+}
+
+func (st *stats) setCapacity(size int) {
+	atomic.StoreInt64(&st.capacity, int64(size))
+}
+
+// increment count of policy switches made by an adaptive cache
+func (st *stats) IncrPolicySwitchCount() uint64 {
+	return atomic.AddUint64(&st.policySwitchCount, 1)
+}
+
+// PolicySwitchCount returns how many times an adaptive cache switched its eviction policy
+func (st *stats) PolicySwitchCount() uint64 {
+	return atomic.LoadUint64(&st.policySwitchCount)
+}
+
+// ActivePolicy returns the eviction type the cache currently uses, or an
+// empty string for a custom Policy
+func (st *stats) ActivePolicy() string {
+	tp, _ := st.activePolicy.Load().(string)
+	return tp
+}
+
+func (st *stats) setActivePolicy(tp string) {
+	st.activePolicy.Store(tp)
+}
+
+// increment count of loader calls retried after an error
+func (st *stats) IncrLoaderRetryCount() uint64 {
+	return atomic.AddUint64(&st.loaderRetryCount, 1)
+}
+
+// LoaderRetryCount returns how many times a failed loader call was retried
+func (st *stats) LoaderRetryCount() uint64 {
+	return atomic.LoadUint64(&st.loaderRetryCount)
+}
+
+// increment count of circuit breaker openings
+func (st *stats) IncrCircuitOpenCount() uint64 {
+	return atomic.AddUint64(&st.circuitOpenCount, 1)
+}
+
+// CircuitOpenCount returns how many times the circuit breaker of the loader opened
+func (st *stats) CircuitOpenCount() uint64 {
+	return atomic.LoadUint64(&st.circuitOpenCount)
+}
+
+// CircuitState returns the state of the circuit breaker of the loader
+func (st *stats) CircuitState() CircuitState {
+	return CircuitState(atomic.LoadInt32(&st.circuitState))
+}
+
+func (st *stats) setCircuitState(state CircuitState) {
+	atomic.StoreInt32(&st.circuitState, int32(state))
+}
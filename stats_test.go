@@ -79,6 +79,86 @@ func TestCacheStats(t *testing.T) {
 			},
 			rate: 0.5,
 		},
+		{
+			builder: func() Cache {
+				cc := New(32).TwoQueue().Build()
+				cc.Set(0, 0)
+				cc.Get(0)
+				cc.Get(1)
+				return cc
+			},
+			rate: 0.5,
+		},
+		{
+			builder: func() Cache {
+				cc := New(32).Sieve().Build()
+				cc.Set(0, 0)
+				cc.Get(0)
+				cc.Get(1)
+				return cc
+			},
+			rate: 0.5,
+		},
+		{
+			builder: func() Cache {
+				cc := New(32).S3FIFO().Build()
+				cc.Set(0, 0)
+				cc.Get(0)
+				cc.Get(1)
+				return cc
+			},
+			rate: 0.5,
+		},
+		{
+			builder: func() Cache {
+				cc := New(32).CLOCK().Build()
+				cc.Set(0, 0)
+				cc.Get(0)
+				cc.Get(1)
+				return cc
+			},
+			rate: 0.5,
+		},
+		{
+			builder: func() Cache {
+				cc := New(32).CLOCKPro().Build()
+				cc.Set(0, 0)
+				cc.Get(0)
+				cc.Get(1)
+				return cc
+			},
+			rate: 0.5,
+		},
+		{
+			builder: func() Cache {
+				cc := New(32).LIRS().Build()
+				cc.Set(0, 0)
+				cc.Get(0)
+				cc.Get(1)
+				return cc
+			},
+			rate: 0.5,
+		},
+		{
+			builder: func() Cache {
+				cc := New(32).GDSF().Build()
+				cc.Set(0, 0)
+				cc.Get(0)
+				cc.Get(1)
+				return cc
+			},
+			rate: 0.5,
+		},
+		{
+			builder: func() Cache {
+				cc := New(32).Adaptive().Build()
+				cc.Set(0, 0)
+				cc.Get(0)
+				cc.Get(1)
+				return cc
+			},
+			rate: 0.5,
+		},
 		{
 			builder: func() Cache {
 				cc := New(32).
@@ -131,6 +211,110 @@ func TestCacheStats(t *testing.T) {
 			},
 			rate: 0.5,
 		},
+		{
+			builder: func() Cache {
+				cc := New(32).
+					TwoQueue().
+					LoaderFunc(getter).
+					Build()
+				cc.Set(0, 0)
+				cc.Get(0)
+				cc.Get(1)
+				return cc
+			},
+			rate: 0.5,
+		},
+		{
+			builder: func() Cache {
+				cc := New(32).
+					Sieve().
+					LoaderFunc(getter).
+					Build()
+				cc.Set(0, 0)
+				cc.Get(0)
+				cc.Get(1)
+				return cc
+			},
+			rate: 0.5,
+		},
+		{
+			builder: func() Cache {
+				cc := New(32).
+					S3FIFO().
+					LoaderFunc(getter).
+					Build()
+				cc.Set(0, 0)
+				cc.Get(0)
+				cc.Get(1)
+				return cc
+			},
+			rate: 0.5,
+		},
+		{
+			builder: func() Cache {
+				cc := New(32).
+					CLOCK().
+					LoaderFunc(getter).
+					Build()
+				cc.Set(0, 0)
+				cc.Get(0)
+				cc.Get(1)
+				return cc
+			},
+			rate: 0.5,
+		},
+		{
+			builder: func() Cache {
+				cc := New(32).
+					CLOCKPro().
+					LoaderFunc(getter).
+					Build()
+				cc.Set(0, 0)
+				cc.Get(0)
+				cc.Get(1)
+				return cc
+			},
+			rate: 0.5,
+		},
+		{
+			builder: func() Cache {
+				cc := New(32).
+					LIRS().
+					LoaderFunc(getter).
+					Build()
+				cc.Set(0, 0)
+				cc.Get(0)
+				cc.Get(1)
+				return cc
+			},
+			rate: 0.5,
+		},
+		{
+			builder: func() Cache {
+				cc := New(32).
+					GDSF().
+					LoaderFunc(getter).
+					Build()
+				cc.Set(0, 0)
+				cc.Get(0)
+				cc.Get(1)
+				return cc
+			},
+			rate: 0.5,
+		},
+		{
+			builder: func() Cache {
+				cc := New(32).
+					Adaptive().
+					LoaderFunc(getter).
+					Build()
+				cc.Set(0, 0)
+				cc.Get(0)
+				cc.Get(1)
+				return cc
+			},
+			rate: 0.5,
+		},
 	}
 
 	for i, cs := range cases {
@@ -0,0 +1,119 @@
+package gcache
+
+import (
+	"container/list"
+)
+
+// TwoQueueCache implements the 2Q algorithm.
+// New items enter the A1in FIFO. Keys evicted from A1in are remembered in
+// the A1out ghost queue, and an item loaded again while its key is still in
+// A1out is promoted to the Am LRU, which holds the frequently used items.
+type TwoQueueCache struct {
+	baseCache
+}
+
+var _ Cache = (*TwoQueueCache)(nil)
+
+func newTwoQueueCache(cb *CacheBuilder) *TwoQueueCache {
+	c := &TwoQueueCache{}
+	buildCache(&c.baseCache, cb, newTwoQueuePolicy())
+	return c
+}
+
+type twoQueuePolicy struct {
+	items map[interface{}]*twoQueueItem
+	a1in  *list.List // FIFO of recently added keys
+	am    *list.List // LRU of frequently used keys
+	a1out *arcList   // ghost keys recently evicted from a1in
+
+	size int
+	kin  int
+	kout int
+}
+
+type twoQueueItem struct {
+	element  *list.Element
+	frequent bool // true if the key lives in am
+}
+
+func newTwoQueuePolicy() *twoQueuePolicy {
+	p := &twoQueuePolicy{}
+	p.reset()
+	return p
+}
+
+func (p *twoQueuePolicy) reset() {
+	p.items = make(map[interface{}]*twoQueueItem)
+	p.a1in = list.New()
+	p.am = list.New()
+	p.a1out = newARCList()
+}
+
+// SetSize derives the A1in and A1out limits from the cache size,
+// using the values suggested by the 2Q paper.
+func (p *twoQueuePolicy) SetSize(size int) {
+	p.size = size
+	p.kin = maxInt(1, size/4)
+	p.kout = maxInt(1, size/2)
+	p.trimGhosts()
+}
+
+func (p *twoQueuePolicy) OnInsert(key interface{}) {
+	item := &twoQueueItem{}
+	if elt := p.a1out.Lookup(key); elt != nil {
+		p.a1out.Remove(key, elt)
+		item.frequent = true
+		item.element = p.am.PushFront(key)
+	} else {
+		item.element = p.a1in.PushFront(key)
+	}
+	p.items[key] = item
+}
+
+// OnAccess leaves a key in A1in where it is: the queue is a FIFO so that
+// correlated references do not promote it.
+func (p *twoQueuePolicy) OnAccess(key interface{}) {
+	if item, ok := p.items[key]; ok && item.frequent {
+		p.am.MoveToFront(item.element)
+	}
+}
+
+func (p *twoQueuePolicy) OnRemove(key interface{}) {
+	if item, ok := p.items[key]; ok {
+		p.removeItem(key, item)
+	}
+}
+
+// Victim takes the oldest key of A1in, which is remembered in A1out, while
+// A1in is over its share. Otherwise it takes the least recently used key of Am.
+func (p *twoQueuePolicy) Victim() (interface{}, bool) {
+	var elt *list.Element
+	if p.a1in.Len() > p.kin || (p.a1in.Len() > 0 && p.am.Len() == 0) {
+		elt = p.a1in.Back()
+		p.a1out.PushFront(elt.Value)
+		p.trimGhosts()
+	} else {
+		elt = p.am.Back()
+	}
+	if elt == nil {
+		return nil, false
+	}
+	key := elt.Value
+	p.removeItem(key, p.items[key])
+	return key, true
+}
+
+func (p *twoQueuePolicy) removeItem(key interface{}, item *twoQueueItem) {
+	if item.frequent {
+		p.am.Remove(item.element)
+	} else {
+		p.a1in.Remove(item.element)
+	}
+	delete(p.items, key)
+}
+
+func (p *twoQueuePolicy) trimGhosts() {
+	for p.a1out.Len() > p.kout {
+		p.a1out.RemoveTail()
+	}
+}
@@ -0,0 +1,176 @@
+package gcache
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestTwoQueueGet(t *testing.T) {
+	size := 1000
+	gc := buildTestCache(t, TYPE_2Q, size)
+	testSetCache(t, gc, size)
+	testGetCache(t, gc, size)
+}
+
+func TestLoadingTwoQueueGet(t *testing.T) {
+	size := 1000
+	numbers := 1000
+	testGetCache(t, buildTestLoadingCache(t, TYPE_2Q, size, loader), numbers)
+}
+
+func TestTwoQueueLength(t *testing.T) {
+	gc := buildTestLoadingCacheWithExpiration(t, TYPE_2Q, 2, time.Millisecond)
+	gc.Get("test1")
+	gc.Get("test2")
+	gc.Get("test3")
+	length := gc.Len(true)
+	expectedLength := 2
+	if length != expectedLength {
+		t.Errorf("Expected length is %v, not %v", expectedLength, length)
+	}
+	time.Sleep(time.Millisecond)
+	gc.Get("test4")
+	length = gc.Len(true)
+	expectedLength = 1
+	if length != expectedLength {
+		t.Errorf("Expected length is %v, not %v", expectedLength, length)
+	}
+}
+
+func TestTwoQueueEvictItem(t *testing.T) {
+	cacheSize := 10
+	numbers := cacheSize + 1
+	gc := buildTestLoadingCache(t, TYPE_2Q, cacheSize, loader)
+
+	for i := 0; i < numbers; i++ {
+		_, err := gc.Get(fmt.Sprintf("Key-%d", i))
+		if err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+	}
+	if l := gc.Len(false); l != cacheSize {
+		t.Errorf("Expected length is %v, not %v", cacheSize, l)
+	}
+}
+
+func TestTwoQueuePurgeCache(t *testing.T) {
+	cacheSize := 10
+	purgeCount := 0
+	gc := New(cacheSize).
+		TwoQueue().
+		LoaderFunc(loader).
+		PurgeVisitorFunc(func(k, v interface{}) {
+			purgeCount++
+		}).
+		Build()
+
+	for i := 0; i < cacheSize; i++ {
+		_, err := gc.Get(fmt.Sprintf("Key-%d", i))
+		if err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+	}
+
+	gc.Purge()
+
+	if purgeCount != cacheSize {
+		t.Errorf("failed to purge everything")
+	}
+}
+
+func TestTwoQueueGetIFPresent(t *testing.T) {
+	testGetIFPresent(t, TYPE_2Q)
+}
+
+func TestTwoQueueHas(t *testing.T) {
+	gc := buildTestLoadingCacheWithExpiration(t, TYPE_2Q, 2, 10*time.Millisecond)
+
+	for i := 0; i < 10; i++ {
+		t.Run(fmt.Sprint(i), func(t *testing.T) {
+			gc.Get("test1")
+			gc.Get("test2")
+
+			if gc.Has("test0") {
+				t.Fatal("should not have test0")
+			}
+			if !gc.Has("test1") {
+				t.Fatal("should have test1")
+			}
+			if !gc.Has("test2") {
+				t.Fatal("should have test2")
+			}
+
+			time.Sleep(20 * time.Millisecond)
+
+			if gc.Has("test0") {
+				t.Fatal("should not have test0")
+			}
+			if gc.Has("test1") {
+				t.Fatal("should not have test1")
+			}
+			if gc.Has("test2") {
+				t.Fatal("should not have test2")
+			}
+		})
+	}
+}
+
+func TestTwoQueuePromotion(t *testing.T) {
+	size := 8
+	gc := buildTestCache(t, TYPE_2Q, size).(*TwoQueueCache)
+	p := gc.policy.(*twoQueuePolicy)
+
+	setItemsByRange(t, gc, 0, size)
+	setItemsByRange(t, gc, size, 2*size)
+	if gc.Has(0) {
+		t.Fatal("0 should be evicted")
+	}
+	// A1out remembers the last size/2 keys evicted from A1in.
+	if p.a1out.Has(3) {
+		t.Fatal("3 should be forgotten")
+	}
+	if !p.a1out.Has(7) {
+		t.Fatal("7 should be remembered in A1out")
+	}
+
+	// Seeing a remembered key again promotes it to Am.
+	gc.Set(7, 7)
+	if !p.items[7].frequent {
+		t.Fatal("7 should be promoted to Am")
+	}
+	if p.a1out.Has(7) {
+		t.Fatal("7 should be removed from A1out")
+	}
+	if l := gc.Len(false); l != size {
+		t.Fatalf("%v != %v", l, size)
+	}
+}
+
+func TestTwoQueueScanResistance(t *testing.T) {
+	size := 100
+	hot := 20
+	gc := buildTestCache(t, TYPE_2Q, size)
+
+	setItemsByRange(t, gc, 0, hot)
+	setItemsByRange(t, gc, 1000, 1000+size-hot)
+	// push the hot keys out of A1in; they are remembered in A1out
+	setItemsByRange(t, gc, 2000, 2000+hot+10)
+	// referencing them again promotes them to Am
+	setItemsByRange(t, gc, 0, hot)
+
+	// a long sequential scan only churns A1in
+	setItemsByRange(t, gc, 10000, 10000+10*size)
+	for i := 0; i < hot; i++ {
+		if _, err := gc.Get(i); err != nil {
+			t.Fatalf("hot key %v should survive the scan: %v", i, err)
+		}
+	}
+
+	lru := buildTestCache(t, TYPE_LRU, size)
+	setItemsByRange(t, lru, 0, hot)
+	setItemsByRange(t, lru, 10000, 10000+10*size)
+	if lru.Has(0) {
+		t.Fatal("LRU should lose the hot keys to the scan")
+	}
+}